@@ -0,0 +1,96 @@
+package hw05parallelexecution
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock is a source of time, replaceable in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// sleep waits for d on the clock. It returns false if stop was closed earlier.
+func sleep(clock Clock, d time.Duration, stop <-chan struct{}) bool {
+	select {
+	case <-clock.After(d):
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// backoff returns the delay before the retry attempt: baseDelay doubled for every
+// previous retry, capped by maxDelay and reduced by a random jitter fraction.
+func backoff(attempt int, baseDelay, maxDelay time.Duration, jitter float64) time.Duration {
+	delay := baseDelay
+	for i := 1; i < attempt && (maxDelay <= 0 || delay < maxDelay); i++ {
+		delay *= 2
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+	if jitter > 0 {
+		delay -= time.Duration(jitter * rand.Float64() * float64(delay)) //nolint:gosec
+	}
+	return delay
+}
+
+// tokenBucket limits the rate of events to rate per second with bursts up to burst.
+type tokenBucket struct {
+	mu     sync.Mutex
+	clock  Clock
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(clock Clock, rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		clock:  clock,
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}
+}
+
+// wait takes a token, blocking until one is available. It returns false if stop was closed earlier.
+func (b *tokenBucket) wait(stop <-chan struct{}) bool {
+	for {
+		b.mu.Lock()
+		now := b.clock.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return true
+		}
+		need := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		if !sleep(b.clock, need, stop) {
+			return false
+		}
+	}
+}
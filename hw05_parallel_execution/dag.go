@@ -0,0 +1,285 @@
+package hw05parallelexecution
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrDuplicateTask     = errors.New("duplicate task")
+	ErrUnknownDependency = errors.New("unknown dependency")
+	ErrDependencyCycle   = errors.New("dependency cycle")
+	ErrDependencyFailed  = errors.New("dependency failed")
+	ErrTasksFailed       = errors.New("tasks failed")
+)
+
+// TaskStatus is the state of a DAG task.
+type TaskStatus int
+
+const (
+	StatusPending TaskStatus = iota
+	StatusSucceeded
+	StatusFailed
+	StatusSkipped
+)
+
+// String returns the status name.
+func (s TaskStatus) String() string {
+	switch s {
+	case StatusPending:
+		return "pending"
+	case StatusSucceeded:
+		return "succeeded"
+	case StatusFailed:
+		return "failed"
+	case StatusSkipped:
+		return "skipped"
+	}
+	return fmt.Sprintf("TaskStatus(%d)", int(s))
+}
+
+// DAGTask is a task which starts after all its dependencies succeed.
+// Among ready tasks the ones with higher Priority start first.
+type DAGTask struct {
+	ID        string
+	DependsOn []string
+	Priority  int
+	Task      Task
+}
+
+// TaskResult is the outcome of a DAG task.
+type TaskResult struct {
+	Status TaskStatus
+	Err    error
+}
+
+// DAGReport contains results of DAG tasks by their IDs.
+type DAGReport map[string]TaskResult
+
+// Count returns the number of tasks with the status.
+func (r DAGReport) Count(status TaskStatus) int {
+	count := 0
+	for _, result := range r {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// dagNode is a DAG task with its position in the graph.
+type dagNode struct {
+	DAGTask
+	index      int
+	waiting    int
+	dependents []*dagNode
+}
+
+// readyQueue orders ready nodes by priority and then by input order.
+type readyQueue []*dagNode
+
+func (q readyQueue) Len() int { return len(q) }
+
+func (q readyQueue) Less(i, j int) bool {
+	if q[i].Priority != q[j].Priority {
+		return q[i].Priority > q[j].Priority
+	}
+	return q[i].index < q[j].index
+}
+
+func (q readyQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *readyQueue) Push(x any) { *q = append(*q, x.(*dagNode)) }
+
+func (q *readyQueue) Pop() any {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
+
+type dagResult struct {
+	node *dagNode
+	err  error
+}
+
+// buildDAG links tasks by their dependencies and checks the graph has no cycles.
+func buildDAG(tasks []DAGTask) ([]*dagNode, error) {
+	nodes := make([]*dagNode, 0, len(tasks))
+	byID := make(map[string]*dagNode, len(tasks))
+	for i, task := range tasks {
+		if _, ok := byID[task.ID]; ok {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateTask, task.ID)
+		}
+		node := &dagNode{DAGTask: task, index: i}
+		nodes = append(nodes, node)
+		byID[task.ID] = node
+	}
+
+	for _, node := range nodes {
+		seen := make(map[string]struct{}, len(node.DependsOn))
+		for _, depID := range node.DependsOn {
+			if _, ok := seen[depID]; ok {
+				continue
+			}
+			seen[depID] = struct{}{}
+			dep, ok := byID[depID]
+			if !ok {
+				return nil, fmt.Errorf("%w: %q depends on %q", ErrUnknownDependency, node.ID, depID)
+			}
+			dep.dependents = append(dep.dependents, node)
+			node.waiting++
+		}
+	}
+
+	// Kahn's algorithm: the nodes left unvisited are on a cycle or depend on one.
+	waiting := make(map[*dagNode]int, len(nodes))
+	queue := make([]*dagNode, 0, len(nodes))
+	for _, node := range nodes {
+		waiting[node] = node.waiting
+		if node.waiting == 0 {
+			queue = append(queue, node)
+		}
+	}
+	for i := 0; i < len(queue); i++ {
+		for _, dependent := range queue[i].dependents {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	if len(queue) < len(nodes) {
+		ids := make([]string, 0, len(nodes)-len(queue))
+		for _, node := range nodes {
+			if waiting[node] > 0 {
+				ids = append(ids, node.ID)
+			}
+		}
+		sort.Strings(ids)
+		return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(ids, ", "))
+	}
+
+	return nodes, nil
+}
+
+// RunDAG runs tasks in n goroutines respecting their dependencies and priorities.
+// Dependents of a failed task are skipped. The graph is validated before any task starts.
+// ErrTasksFailed is returned along with the report if any task failed.
+// Observers get tasks by their input index; the errors limit options are not used.
+func RunDAG(tasks []DAGTask, n int, opts ...Option) (DAGReport, error) {
+	nodes, err := buildDAG(tasks)
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		n = 1
+	}
+	e := newExecutor(opts)
+
+	report := make(DAGReport, len(nodes))
+	ready := &readyQueue{}
+	for _, node := range nodes {
+		report[node.ID] = TaskResult{Status: StatusPending}
+		if node.waiting == 0 {
+			heap.Push(ready, node)
+		}
+	}
+
+	var wg sync.WaitGroup
+	chNodes := make(chan *dagNode)
+	chResults := make(chan dagResult)
+	stop := make(chan struct{})
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for node := range chNodes {
+				var taskStart time.Time
+				err := e.execute(node.Task, stop, func() {
+					e.observer.OnTaskStart(node.index)
+					taskStart = e.clock.Now()
+				})
+				if !errors.Is(err, errNotStarted) {
+					e.observer.OnTaskFinish(node.index, e.clock.Now().Sub(taskStart), err)
+				}
+				chResults <- dagResult{node: node, err: err}
+			}
+		}()
+	}
+
+	var panicErr *PanicError
+	failed := 0
+	running := 0
+	for running > 0 || (ready.Len() > 0 && panicErr == nil) {
+		var send chan *dagNode
+		var next *dagNode
+		if ready.Len() > 0 && panicErr == nil {
+			send = chNodes
+			next = (*ready)[0]
+		}
+
+		select {
+		case send <- next:
+			heap.Pop(ready)
+			running++
+		case res := <-chResults:
+			running--
+			if errors.Is(res.err, errNotStarted) {
+				continue
+			}
+			if res.err == nil {
+				report[res.node.ID] = TaskResult{Status: StatusSucceeded}
+				for _, dependent := range res.node.dependents {
+					dependent.waiting--
+					if dependent.waiting == 0 && report[dependent.ID].Status == StatusPending {
+						heap.Push(ready, dependent)
+					}
+				}
+				continue
+			}
+
+			failed++
+			report[res.node.ID] = TaskResult{Status: StatusFailed, Err: res.err}
+			skipDependents(res.node, report)
+			var pErr *PanicError
+			if e.repanic && panicErr == nil && errors.As(res.err, &pErr) {
+				panicErr = pErr
+				close(stop)
+			}
+		}
+	}
+	close(chNodes)
+	wg.Wait()
+
+	if skipped := len(nodes) - report.Count(StatusSucceeded) - failed; skipped > 0 {
+		e.observer.OnCancel(skipped)
+	}
+	if panicErr != nil {
+		panic(panicErr)
+	}
+	if failed > 0 {
+		return report, fmt.Errorf("%w: %d of %d", ErrTasksFailed, failed, len(nodes))
+	}
+	return report, nil
+}
+
+// skipDependents marks all pending tasks depending on the failed one as skipped.
+func skipDependents(failed *dagNode, report DAGReport) {
+	for _, dependent := range failed.dependents {
+		if report[dependent.ID].Status != StatusPending {
+			continue
+		}
+		report[dependent.ID] = TaskResult{
+			Status: StatusSkipped,
+			Err:    fmt.Errorf("%w: %q", ErrDependencyFailed, failed.ID),
+		}
+		skipDependents(dependent, report)
+	}
+}
@@ -0,0 +1,263 @@
+package hw05parallelexecution
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestRunDAG(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("dependencies finish before dependents start", func(t *testing.T) {
+		var mu sync.Mutex
+		finished := make(map[string]bool)
+
+		task := func(id string, deps ...string) DAGTask {
+			return DAGTask{
+				ID:        id,
+				DependsOn: deps,
+				Task: func() error {
+					mu.Lock()
+					for _, dep := range deps {
+						if !finished[dep] {
+							mu.Unlock()
+							return fmt.Errorf("%s started before %s", id, dep)
+						}
+					}
+					mu.Unlock()
+
+					time.Sleep(time.Millisecond)
+
+					mu.Lock()
+					finished[id] = true
+					mu.Unlock()
+					return nil
+				},
+			}
+		}
+
+		tasks := []DAGTask{
+			task("deploy", "build", "test"),
+			task("build", "fetch"),
+			task("test", "build"),
+			task("fetch"),
+			task("lint", "fetch"),
+			task("notify", "deploy", "lint"),
+		}
+
+		report, err := RunDAG(tasks, 3)
+		require.NoError(t, err)
+		require.Len(t, report, len(tasks))
+		for id, result := range report {
+			require.Equalf(t, StatusSucceeded, result.Status, "task %s: %v", id, result.Err)
+		}
+	})
+
+	t.Run("ready tasks start by priority", func(t *testing.T) {
+		var mu sync.Mutex
+		order := make([]string, 0)
+
+		task := func(id string, priority int, deps ...string) DAGTask {
+			return DAGTask{
+				ID:        id,
+				DependsOn: deps,
+				Priority:  priority,
+				Task: func() error {
+					mu.Lock()
+					defer mu.Unlock()
+					order = append(order, id)
+					return nil
+				},
+			}
+		}
+
+		tasks := []DAGTask{
+			task("root", 0),
+			task("low", 1, "root"),
+			task("high", 10, "root"),
+			task("middle", 5, "root"),
+			task("same-middle", 5, "root"),
+		}
+
+		_, err := RunDAG(tasks, 1)
+		require.NoError(t, err)
+		require.Equal(t, []string{"root", "high", "middle", "same-middle", "low"}, order)
+	})
+
+	t.Run("dependents of failed task are skipped", func(t *testing.T) {
+		var runTasksCount int32
+		errFailed := errors.New("failed")
+
+		task := func(id string, err error, deps ...string) DAGTask {
+			return DAGTask{
+				ID:        id,
+				DependsOn: deps,
+				Task: func() error {
+					atomic.AddInt32(&runTasksCount, 1)
+					return err
+				},
+			}
+		}
+
+		tasks := []DAGTask{
+			task("a", nil),
+			task("b", errFailed, "a"),
+			task("c", nil, "b"),
+			task("d", nil, "c", "a"),
+			task("e", nil, "a"),
+			task("f", nil),
+		}
+
+		report, err := RunDAG(tasks, 2)
+		require.ErrorIs(t, err, ErrTasksFailed)
+		require.Equal(t, int32(4), runTasksCount)
+
+		require.Equal(t, StatusSucceeded, report["a"].Status)
+		require.Equal(t, StatusFailed, report["b"].Status)
+		require.ErrorIs(t, report["b"].Err, errFailed)
+		require.Equal(t, StatusSkipped, report["c"].Status)
+		require.ErrorIs(t, report["c"].Err, ErrDependencyFailed)
+		require.Equal(t, StatusSkipped, report["d"].Status)
+		require.Equal(t, StatusSucceeded, report["e"].Status)
+		require.Equal(t, StatusSucceeded, report["f"].Status)
+
+		require.Equal(t, 3, report.Count(StatusSucceeded))
+		require.Equal(t, 1, report.Count(StatusFailed))
+		require.Equal(t, 2, report.Count(StatusSkipped))
+	})
+
+	t.Run("tasks run concurrently up to n", func(t *testing.T) {
+		tasksCount := 20
+		workersCount := 4
+		var running, maxRunning int32
+
+		tasks := make([]DAGTask, 0, tasksCount)
+		for i := 0; i < tasksCount; i++ {
+			tasks = append(tasks, DAGTask{
+				ID: fmt.Sprintf("task-%d", i),
+				Task: func() error {
+					current := atomic.AddInt32(&running, 1)
+					for {
+						prev := atomic.LoadInt32(&maxRunning)
+						if current <= prev || atomic.CompareAndSwapInt32(&maxRunning, prev, current) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+					atomic.AddInt32(&running, -1)
+					return nil
+				},
+			})
+		}
+
+		_, err := RunDAG(tasks, workersCount)
+		require.NoError(t, err)
+		require.LessOrEqual(t, maxRunning, int32(workersCount))
+		require.Greater(t, maxRunning, int32(1), "tasks were run sequentially?")
+	})
+
+	t.Run("observer", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		tasks := []DAGTask{
+			{ID: "a", Task: func() error { return nil }},
+			{ID: "b", DependsOn: []string{"a"}, Task: func() error { return errFailed }},
+			{ID: "c", DependsOn: []string{"b"}, Task: func() error { return nil }},
+			{ID: "d", DependsOn: []string{"c"}, Task: func() error { return nil }},
+		}
+
+		observer := newRecordingObserver()
+		_, err := RunDAG(tasks, 2, WithObserver(observer))
+		require.ErrorIs(t, err, ErrTasksFailed)
+
+		require.Equal(t, []int{0, 1}, observer.started)
+		require.NoError(t, observer.finished[0])
+		require.ErrorIs(t, observer.finished[1], errFailed)
+		require.Equal(t, 2, observer.canceled)
+		require.Empty(t, observer.limitReached)
+	})
+
+	t.Run("panics are reported as failures", func(t *testing.T) {
+		tasks := []DAGTask{
+			{ID: "a", Task: func() error { panic("boom") }},
+			{ID: "b", DependsOn: []string{"a"}, Task: func() error { return nil }},
+		}
+
+		report, err := RunDAG(tasks, 2)
+		require.ErrorIs(t, err, ErrTasksFailed)
+		require.ErrorIs(t, report["a"].Err, ErrTaskPanicked)
+		require.Equal(t, StatusSkipped, report["b"].Status)
+	})
+}
+
+func TestRunDAGValidation(t *testing.T) {
+	noop := func() error { return nil }
+	var runTasksCount int32
+	counted := func() error {
+		atomic.AddInt32(&runTasksCount, 1)
+		return nil
+	}
+
+	tests := []struct {
+		name        string
+		tasks       []DAGTask
+		expectedErr error
+	}{
+		{
+			name: "duplicate task",
+			tasks: []DAGTask{
+				{ID: "a", Task: noop},
+				{ID: "a", Task: noop},
+			},
+			expectedErr: ErrDuplicateTask,
+		},
+		{
+			name: "unknown dependency",
+			tasks: []DAGTask{
+				{ID: "a", DependsOn: []string{"b"}, Task: noop},
+			},
+			expectedErr: ErrUnknownDependency,
+		},
+		{
+			name: "self dependency",
+			tasks: []DAGTask{
+				{ID: "a", DependsOn: []string{"a"}, Task: noop},
+			},
+			expectedErr: ErrDependencyCycle,
+		},
+		{
+			name: "cycle",
+			tasks: []DAGTask{
+				{ID: "root", Task: counted},
+				{ID: "a", DependsOn: []string{"root", "c"}, Task: counted},
+				{ID: "b", DependsOn: []string{"a"}, Task: counted},
+				{ID: "c", DependsOn: []string{"b"}, Task: counted},
+			},
+			expectedErr: ErrDependencyCycle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := RunDAG(tt.tasks, 2)
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.Nil(t, report)
+		})
+	}
+
+	require.Zero(t, runTasksCount, "tasks were started before validation")
+
+	_, err := buildDAG([]DAGTask{
+		{ID: "a", DependsOn: []string{"c"}},
+		{ID: "b", DependsOn: []string{"a"}},
+		{ID: "c", DependsOn: []string{"b"}},
+		{ID: "d", DependsOn: []string{"c"}},
+	})
+	require.EqualError(t, err, "dependency cycle: a, b, c, d")
+}
@@ -0,0 +1,52 @@
+package hw05parallelexecution
+
+import "time"
+
+// Observer is notified about the progress of Run and RunDAG.
+// Its methods are called from worker goroutines and must be safe for concurrent use.
+type Observer interface {
+	// OnTaskStart is called before the task with the index starts.
+	OnTaskStart(index int)
+	// OnTaskFinish is called after the task finishes with its final error.
+	OnTaskFinish(index int, duration time.Duration, err error)
+	// OnCancel is called when Run stops leaving skipped tasks not started.
+	// RunDAG reports the tasks skipped because of failed dependencies.
+	OnCancel(skipped int)
+	// OnLimitReached is called once when the errors limit is reached.
+	OnLimitReached(errorsCount int)
+}
+
+// Summary describes the result of Run.
+type Summary struct {
+	Succeeded int
+	Failed    int
+	Skipped   int
+	WallTime  time.Duration
+}
+
+// multiObserver notifies all observers in turn.
+type multiObserver []Observer
+
+func (m multiObserver) OnTaskStart(index int) {
+	for _, o := range m {
+		o.OnTaskStart(index)
+	}
+}
+
+func (m multiObserver) OnTaskFinish(index int, duration time.Duration, err error) {
+	for _, o := range m {
+		o.OnTaskFinish(index, duration, err)
+	}
+}
+
+func (m multiObserver) OnCancel(skipped int) {
+	for _, o := range m {
+		o.OnCancel(skipped)
+	}
+}
+
+func (m multiObserver) OnLimitReached(errorsCount int) {
+	for _, o := range m {
+		o.OnLimitReached(errorsCount)
+	}
+}
@@ -0,0 +1,136 @@
+package hw05parallelexecution
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+type recordingObserver struct {
+	mu           sync.Mutex
+	started      []int
+	finished     map[int]error
+	durations    map[int]time.Duration
+	canceled     int
+	limitReached []int
+}
+
+func newRecordingObserver() *recordingObserver {
+	return &recordingObserver{
+		finished:  make(map[int]error),
+		durations: make(map[int]time.Duration),
+	}
+}
+
+func (o *recordingObserver) OnTaskStart(index int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.started = append(o.started, index)
+}
+
+func (o *recordingObserver) OnTaskFinish(index int, duration time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.finished[index] = err
+	o.durations[index] = duration
+}
+
+func (o *recordingObserver) OnCancel(skipped int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.canceled += skipped
+}
+
+func (o *recordingObserver) OnLimitReached(errorsCount int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.limitReached = append(o.limitReached, errorsCount)
+}
+
+func TestRunObserver(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("all tasks succeed", func(t *testing.T) {
+		tasksCount := 10
+		clock := newFakeClock()
+		tasks := make([]Task, 0, tasksCount)
+		for i := 0; i < tasksCount; i++ {
+			tasks = append(tasks, func() error {
+				clock.Advance(time.Duration(i+1) * time.Millisecond)
+				return nil
+			})
+		}
+
+		observer := newRecordingObserver()
+		summary, err := RunWithSummary(tasks, 1, 1, WithObserver(observer), WithClock(clock))
+		require.NoError(t, err)
+
+		require.Equal(t, Summary{Succeeded: tasksCount, WallTime: 55 * time.Millisecond}, summary)
+		require.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, observer.started)
+		for i := 0; i < tasksCount; i++ {
+			require.NoError(t, observer.finished[i])
+			require.Equal(t, time.Duration(i+1)*time.Millisecond, observer.durations[i])
+		}
+		require.Zero(t, observer.canceled)
+		require.Empty(t, observer.limitReached)
+	})
+
+	t.Run("errors limit is reached", func(t *testing.T) {
+		tasksCount := 50
+		tasks := make([]Task, 0, tasksCount)
+		for i := 0; i < tasksCount; i++ {
+			err := fmt.Errorf("error from task %d", i)
+			tasks = append(tasks, func() error {
+				return err
+			})
+		}
+
+		workersCount := 5
+		maxErrorsCount := 10
+		observer := newRecordingObserver()
+		summary, err := RunWithSummary(tasks, workersCount, maxErrorsCount, WithObserver(observer))
+		require.ErrorIs(t, err, ErrErrorsLimitExceeded)
+
+		require.Zero(t, summary.Succeeded)
+		require.GreaterOrEqual(t, summary.Failed, maxErrorsCount)
+		require.LessOrEqual(t, summary.Failed, workersCount+maxErrorsCount)
+		require.Equal(t, tasksCount, summary.Failed+summary.Skipped)
+
+		require.Equal(t, []int{maxErrorsCount}, observer.limitReached)
+		require.Equal(t, summary.Skipped, observer.canceled)
+		require.Len(t, observer.finished, summary.Failed)
+		sort.Ints(observer.started)
+		for i, index := range observer.started {
+			require.Equal(t, i, index, "tasks must start in order")
+			require.Error(t, observer.finished[index])
+		}
+	})
+
+	t.Run("zero limit", func(t *testing.T) {
+		observer := newRecordingObserver()
+		tasks := []Task{func() error { return nil }, func() error { return nil }}
+
+		summary, err := RunWithSummary(tasks, 1, 0, WithObserver(observer))
+		require.True(t, errors.Is(err, ErrErrorsLimitExceeded))
+		require.Equal(t, Summary{Skipped: 2}, summary)
+		require.Equal(t, []int{0}, observer.limitReached)
+		require.Equal(t, 2, observer.canceled)
+		require.Empty(t, observer.started)
+	})
+
+	t.Run("several observers", func(t *testing.T) {
+		first, second := newRecordingObserver(), newRecordingObserver()
+		tasks := []Task{func() error { return nil }}
+
+		_, err := RunWithSummary(tasks, 1, 1, WithObserver(first), WithObserver(second))
+		require.NoError(t, err)
+		require.Equal(t, []int{0}, first.started)
+		require.Equal(t, []int{0}, second.started)
+	})
+}
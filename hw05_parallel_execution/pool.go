@@ -0,0 +1,252 @@
+package hw05parallelexecution
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const DefaultIdleTimeout = time.Second
+
+var ErrPoolClosed = errors.New("pool is closed")
+
+// PoolOption configures Pool.
+type PoolOption func(*Pool)
+
+// WithIdleTimeout sets how long an extra worker waits for a task before exiting.
+func WithIdleTimeout(timeout time.Duration) PoolOption {
+	return func(p *Pool) {
+		p.idleTimeout = timeout
+	}
+}
+
+// WithErrorHandler sets the function called with every task error.
+func WithErrorHandler(handler func(error)) PoolOption {
+	return func(p *Pool) {
+		p.onError = handler
+	}
+}
+
+// Pool runs submitted tasks in a number of workers between min and max.
+type Pool struct {
+	minWorkers  int
+	maxWorkers  int
+	idleTimeout time.Duration
+	onError     func(error)
+
+	queue chan Task
+	abort chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	workers int
+	idle    int
+	failed  int
+
+	submitters sync.WaitGroup
+	wg         sync.WaitGroup
+}
+
+// NewPool creates a pool with queueSize buffered tasks and starts minWorkers workers.
+func NewPool(minWorkers, maxWorkers, queueSize int, opts ...PoolOption) *Pool {
+	if maxWorkers < 1 {
+		maxWorkers = 1
+	}
+	if minWorkers < 0 {
+		minWorkers = 0
+	}
+	if minWorkers > maxWorkers {
+		minWorkers = maxWorkers
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	p := &Pool{
+		minWorkers:  minWorkers,
+		maxWorkers:  maxWorkers,
+		idleTimeout: DefaultIdleTimeout,
+		queue:       make(chan Task, queueSize),
+		abort:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.mu.Lock()
+	for i := 0; i < p.minWorkers; i++ {
+		p.spawn()
+	}
+	p.mu.Unlock()
+
+	return p
+}
+
+// Submit puts the task into the queue, blocking while the queue is full.
+func (p *Pool) Submit(task Task) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	p.submitters.Add(1)
+	p.mu.Unlock()
+	defer p.submitters.Done()
+
+	select {
+	case p.queue <- task:
+	default:
+		// The queue is full, so the task itself waits for a worker.
+		p.grow(1)
+		select {
+		case p.queue <- task:
+		case <-p.abort:
+			return ErrPoolClosed
+		}
+	}
+	p.grow(0)
+
+	return nil
+}
+
+// Consume submits tasks from the channel until it is closed or the pool is shut down.
+func (p *Pool) Consume(tasks <-chan Task) error {
+	for task := range tasks {
+		if err := p.Submit(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueueDepth returns the number of tasks waiting for a worker.
+func (p *Pool) QueueDepth() int {
+	return len(p.queue)
+}
+
+// Workers returns the number of running workers.
+func (p *Pool) Workers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.workers
+}
+
+// Failed returns the number of tasks finished with an error.
+func (p *Pool) Failed() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.failed
+}
+
+// Shutdown stops accepting tasks and waits until the queued ones are done.
+// If ctx expires first, the queue is abandoned and ctx error is returned.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		p.submitters.Wait()
+		close(p.queue)
+		p.wg.Wait()
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		close(p.abort)
+		return ctx.Err()
+	}
+}
+
+// grow starts a worker if queued and waiting tasks outnumber idle workers.
+func (p *Pool) grow(waiting int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.workers < p.maxWorkers && p.idle < len(p.queue)+waiting {
+		p.spawn()
+	}
+}
+
+// spawn starts a new worker. Must be called with p.mu held.
+func (p *Pool) spawn() {
+	p.workers++
+	p.wg.Add(1)
+	go p.worker()
+}
+
+// worker runs tasks from the queue and exits when it is not needed anymore.
+func (p *Pool) worker() {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.abort:
+			p.exit()
+			return
+		default:
+		}
+
+		p.mu.Lock()
+		p.idle++
+		p.mu.Unlock()
+
+		timer := time.NewTimer(p.idleTimeout)
+		select {
+		case task, ok := <-p.queue:
+			timer.Stop()
+			p.mu.Lock()
+			p.idle--
+			p.mu.Unlock()
+			if !ok {
+				p.exit()
+				return
+			}
+			p.run(task)
+		case <-timer.C:
+			p.mu.Lock()
+			p.idle--
+			if p.workers > p.minWorkers && len(p.queue) == 0 {
+				p.workers--
+				p.mu.Unlock()
+				return
+			}
+			p.mu.Unlock()
+		case <-p.abort:
+			timer.Stop()
+			p.mu.Lock()
+			p.idle--
+			p.mu.Unlock()
+			p.exit()
+			return
+		}
+	}
+}
+
+// exit unregisters the worker.
+func (p *Pool) exit() {
+	p.mu.Lock()
+	p.workers--
+	p.mu.Unlock()
+}
+
+// run executes the task and records its error.
+func (p *Pool) run(task Task) {
+	err := safeRun(task)
+	if err == nil {
+		return
+	}
+	p.mu.Lock()
+	p.failed++
+	p.mu.Unlock()
+	if p.onError != nil {
+		p.onError(err)
+	}
+}
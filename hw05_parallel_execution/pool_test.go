@@ -0,0 +1,186 @@
+package hw05parallelexecution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestPool(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("all submitted tasks are done", func(t *testing.T) {
+		tasksCount := 100
+		var runTasksCount int32
+
+		pool := NewPool(2, 5, 10)
+		for i := 0; i < tasksCount; i++ {
+			err := pool.Submit(func() error {
+				atomic.AddInt32(&runTasksCount, 1)
+				return nil
+			})
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, pool.Shutdown(context.Background()))
+		require.Equal(t, int32(tasksCount), runTasksCount, "not all tasks were completed")
+		require.Equal(t, 0, pool.Workers())
+	})
+
+	t.Run("pool without permanent workers", func(t *testing.T) {
+		var runTasksCount int32
+
+		pool := NewPool(0, 1, 0)
+		require.Equal(t, 0, pool.Workers())
+
+		err := pool.Submit(func() error {
+			atomic.AddInt32(&runTasksCount, 1)
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, pool.Shutdown(context.Background()))
+		require.Equal(t, int32(1), runTasksCount)
+	})
+
+	t.Run("workers grow up to max and shrink to min", func(t *testing.T) {
+		minWorkers, maxWorkers := 1, 4
+		release := make(chan struct{})
+
+		pool := NewPool(minWorkers, maxWorkers, 10, WithIdleTimeout(10*time.Millisecond))
+		for i := 0; i < 10; i++ {
+			require.NoError(t, pool.Submit(func() error {
+				<-release
+				return nil
+			}))
+		}
+
+		require.Eventually(t, func() bool {
+			return pool.Workers() == maxWorkers
+		}, time.Second, time.Millisecond)
+		require.Equal(t, 10-maxWorkers, pool.QueueDepth())
+
+		close(release)
+		require.Eventually(t, func() bool {
+			return pool.Workers() == minWorkers && pool.QueueDepth() == 0
+		}, time.Second, time.Millisecond)
+
+		require.NoError(t, pool.Shutdown(context.Background()))
+	})
+
+	t.Run("shutdown drains queued tasks", func(t *testing.T) {
+		tasksCount := 20
+		var runTasksCount int32
+		release := make(chan struct{})
+
+		pool := NewPool(1, 1, tasksCount)
+		for i := 0; i < tasksCount; i++ {
+			require.NoError(t, pool.Submit(func() error {
+				<-release
+				atomic.AddInt32(&runTasksCount, 1)
+				return nil
+			}))
+		}
+
+		shutdownErr := make(chan error)
+		go func() {
+			shutdownErr <- pool.Shutdown(context.Background())
+		}()
+
+		require.Eventually(t, func() bool {
+			return errors.Is(pool.Submit(func() error { return nil }), ErrPoolClosed)
+		}, time.Second, time.Millisecond)
+
+		close(release)
+		require.NoError(t, <-shutdownErr)
+		require.Equal(t, int32(tasksCount), runTasksCount, "queued tasks were dropped")
+	})
+
+	t.Run("shutdown is interrupted by context", func(t *testing.T) {
+		var runTasksCount int32
+		release := make(chan struct{})
+
+		pool := NewPool(1, 1, 10)
+		for i := 0; i < 10; i++ {
+			require.NoError(t, pool.Submit(func() error {
+				<-release
+				atomic.AddInt32(&runTasksCount, 1)
+				return nil
+			}))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := pool.Shutdown(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		close(release)
+		require.Eventually(t, func() bool {
+			return pool.Workers() == 0
+		}, time.Second, time.Millisecond)
+		require.Less(t, atomic.LoadInt32(&runTasksCount), int32(10), "queue was not abandoned")
+		require.ErrorIs(t, pool.Shutdown(context.Background()), ErrPoolClosed)
+	})
+
+	t.Run("tasks from channel", func(t *testing.T) {
+		tasksCount := 50
+		var runTasksCount int32
+
+		tasks := make(chan Task)
+		go func() {
+			defer close(tasks)
+			for i := 0; i < tasksCount; i++ {
+				tasks <- func() error {
+					atomic.AddInt32(&runTasksCount, 1)
+					return nil
+				}
+			}
+		}()
+
+		pool := NewPool(1, 3, 5)
+		require.NoError(t, pool.Consume(tasks))
+		require.NoError(t, pool.Shutdown(context.Background()))
+		require.Equal(t, int32(tasksCount), runTasksCount, "not all tasks were completed")
+	})
+
+	t.Run("errors and panics are reported", func(t *testing.T) {
+		var mu sync.Mutex
+		var errs []error
+
+		pool := NewPool(1, 2, 5, WithErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		}))
+		for i := 0; i < 10; i++ {
+			require.NoError(t, pool.Submit(func() error {
+				switch i % 3 {
+				case 0:
+					return fmt.Errorf("error from task %d", i)
+				case 1:
+					panic(i)
+				default:
+					return nil
+				}
+			}))
+		}
+		require.NoError(t, pool.Shutdown(context.Background()))
+
+		require.Equal(t, 7, pool.Failed())
+		require.Len(t, errs, 7)
+		panics := 0
+		for _, err := range errs {
+			if errors.Is(err, ErrTaskPanicked) {
+				panics++
+			}
+		}
+		require.Equal(t, 3, panics)
+	})
+}
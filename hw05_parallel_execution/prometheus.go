@@ -0,0 +1,135 @@
+package hw05parallelexecution
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the upper bounds of the task duration histogram in seconds.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// PrometheusExporter is an Observer which exposes metrics in the Prometheus text format.
+type PrometheusExporter struct {
+	namespace string
+	buckets   []float64
+
+	mu           sync.Mutex
+	started      int64
+	succeeded    int64
+	failed       int64
+	canceled     int64
+	limitReached int64
+	running      int64
+	counts       []int64
+	sum          float64
+}
+
+// NewPrometheusExporter creates the exporter with metric names prefixed by namespace.
+func NewPrometheusExporter(namespace string) *PrometheusExporter {
+	return &PrometheusExporter{
+		namespace: namespace,
+		buckets:   DefaultDurationBuckets,
+		counts:    make([]int64, len(DefaultDurationBuckets)),
+	}
+}
+
+func (p *PrometheusExporter) OnTaskStart(_ int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started++
+	p.running++
+}
+
+func (p *PrometheusExporter) OnTaskFinish(_ int, duration time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running--
+	if err == nil {
+		p.succeeded++
+	} else {
+		p.failed++
+	}
+
+	seconds := duration.Seconds()
+	p.sum += seconds
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			p.counts[i]++
+		}
+	}
+}
+
+func (p *PrometheusExporter) OnCancel(skipped int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.canceled += int64(skipped)
+}
+
+func (p *PrometheusExporter) OnLimitReached(_ int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.limitReached++
+}
+
+// WriteTo writes the current metrics to w.
+func (p *PrometheusExporter) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var buf bytes.Buffer
+	p.writeMetric(&buf, "tasks_started_total", "counter", "Number of started tasks.")
+	p.writeSample(&buf, "tasks_started_total", "", float64(p.started))
+
+	p.writeMetric(&buf, "tasks_finished_total", "counter", "Number of finished tasks by result.")
+	p.writeSample(&buf, "tasks_finished_total", `result="success"`, float64(p.succeeded))
+	p.writeSample(&buf, "tasks_finished_total", `result="error"`, float64(p.failed))
+
+	p.writeMetric(&buf, "tasks_canceled_total", "counter", "Number of tasks not started because of a stop.")
+	p.writeSample(&buf, "tasks_canceled_total", "", float64(p.canceled))
+
+	p.writeMetric(&buf, "errors_limit_reached_total", "counter", "Number of runs stopped by the errors limit.")
+	p.writeSample(&buf, "errors_limit_reached_total", "", float64(p.limitReached))
+
+	p.writeMetric(&buf, "tasks_running", "gauge", "Number of running tasks.")
+	p.writeSample(&buf, "tasks_running", "", float64(p.running))
+
+	p.writeMetric(&buf, "task_duration_seconds", "histogram", "Duration of finished tasks.")
+	for i, bound := range p.buckets {
+		le := `le="` + strconv.FormatFloat(bound, 'g', -1, 64) + `"`
+		p.writeSample(&buf, "task_duration_seconds_bucket", le, float64(p.counts[i]))
+	}
+	p.writeSample(&buf, "task_duration_seconds_bucket", `le="+Inf"`, float64(p.succeeded+p.failed))
+	p.writeSample(&buf, "task_duration_seconds_sum", "", p.sum)
+	p.writeSample(&buf, "task_duration_seconds_count", "", float64(p.succeeded+p.failed))
+
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP serves the metrics for a Prometheus scraper.
+func (p *PrometheusExporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = p.WriteTo(w)
+}
+
+func (p *PrometheusExporter) name(metric string) string {
+	if p.namespace == "" {
+		return metric
+	}
+	return p.namespace + "_" + metric
+}
+
+func (p *PrometheusExporter) writeMetric(buf *bytes.Buffer, metric, typ, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", p.name(metric), help, p.name(metric), typ)
+}
+
+func (p *PrometheusExporter) writeSample(buf *bytes.Buffer, metric, labels string, value float64) {
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(buf, "%s%s %s\n", p.name(metric), labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
@@ -0,0 +1,81 @@
+package hw05parallelexecution
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusExporter(t *testing.T) {
+	t.Run("metrics text", func(t *testing.T) {
+		exporter := NewPrometheusExporter("batch")
+		exporter.buckets = []float64{0.1, 1}
+		exporter.counts = make([]int64, 2)
+
+		exporter.OnTaskStart(0)
+		exporter.OnTaskStart(1)
+		exporter.OnTaskStart(2)
+		exporter.OnTaskFinish(0, 50*time.Millisecond, nil)
+		exporter.OnTaskFinish(1, 500*time.Millisecond, errors.New("failed"))
+		exporter.OnLimitReached(1)
+		exporter.OnCancel(7)
+
+		var sb strings.Builder
+		_, err := exporter.WriteTo(&sb)
+		require.NoError(t, err)
+
+		expected := `# HELP batch_tasks_started_total Number of started tasks.
+# TYPE batch_tasks_started_total counter
+batch_tasks_started_total 3
+# HELP batch_tasks_finished_total Number of finished tasks by result.
+# TYPE batch_tasks_finished_total counter
+batch_tasks_finished_total{result="success"} 1
+batch_tasks_finished_total{result="error"} 1
+# HELP batch_tasks_canceled_total Number of tasks not started because of a stop.
+# TYPE batch_tasks_canceled_total counter
+batch_tasks_canceled_total 7
+# HELP batch_errors_limit_reached_total Number of runs stopped by the errors limit.
+# TYPE batch_errors_limit_reached_total counter
+batch_errors_limit_reached_total 1
+# HELP batch_tasks_running Number of running tasks.
+# TYPE batch_tasks_running gauge
+batch_tasks_running 1
+# HELP batch_task_duration_seconds Duration of finished tasks.
+# TYPE batch_task_duration_seconds histogram
+batch_task_duration_seconds_bucket{le="0.1"} 1
+batch_task_duration_seconds_bucket{le="1"} 2
+batch_task_duration_seconds_bucket{le="+Inf"} 2
+batch_task_duration_seconds_sum 0.55
+batch_task_duration_seconds_count 2
+`
+		require.Equal(t, expected, sb.String())
+	})
+
+	t.Run("observes run", func(t *testing.T) {
+		exporter := NewPrometheusExporter("")
+		tasks := []Task{
+			func() error { return nil },
+			func() error { return errors.New("failed") },
+			func() error { return nil },
+		}
+
+		err := Run(tasks, 2, 5, WithObserver(exporter))
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		exporter.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+		body := rec.Body.String()
+		require.Contains(t, body, "\ntasks_started_total 3\n")
+		require.Contains(t, body, "\ntasks_finished_total{result=\"success\"} 2\n")
+		require.Contains(t, body, "\ntasks_finished_total{result=\"error\"} 1\n")
+		require.Contains(t, body, "\ntask_duration_seconds_count 3\n")
+	})
+}
@@ -2,36 +2,278 @@ package hw05parallelexecution
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
 	"sync"
+	"time"
 )
 
-var ErrErrorsLimitExceeded = errors.New("errors limit exceeded")
+const (
+	DefaultJitter = 0.5
+
+	// ratioTolerance absorbs rounding errors of the error ratio.
+	ratioTolerance = 1e-9
+)
+
+var (
+	ErrErrorsLimitExceeded = errors.New("errors limit exceeded")
+	ErrTaskPanicked        = errors.New("task panicked")
+
+	// errNotStarted is returned for the task abandoned before its first attempt.
+	errNotStarted = errors.New("task not started")
+)
 
 type Task func() error
 
+type indexedTask struct {
+	index int
+	task  Task
+}
+
+// PanicError is the error produced by a task which panicked.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// Error returns the panic value together with the stack trace.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v: %v\n%s", ErrTaskPanicked, e.Value, e.Stack)
+}
+
+// Unwrap allows to match the error with ErrTaskPanicked.
+func (e *PanicError) Unwrap() error {
+	return ErrTaskPanicked
+}
+
+// ZeroLimitPolicy defines how Run treats m <= 0.
+type ZeroLimitPolicy int
+
+const (
+	// ZeroLimitReject makes Run return ErrErrorsLimitExceeded without running tasks.
+	ZeroLimitReject ZeroLimitPolicy = iota
+	// ZeroLimitIgnoreErrors makes Run run all tasks ignoring their errors.
+	ZeroLimitIgnoreErrors
+	// ZeroLimitStopOnError makes Run stop on the first error.
+	ZeroLimitStopOnError
+)
+
+// Option configures Run.
+type Option func(*options)
+
+type options struct {
+	repanic bool
+
+	zeroLimit    ZeroLimitPolicy
+	ignoreErrors bool
+	useRatio     bool
+	errorRatio   float64
+
+	retries   int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	jitter    float64
+
+	rate  float64
+	burst int
+
+	clock Clock
+
+	observer multiObserver
+}
+
+// WithRepanic makes Run panic with the first recovered task panic
+// after all workers are stopped instead of counting it as an error.
+func WithRepanic() Option {
+	return func(o *options) {
+		o.repanic = true
+	}
+}
+
+// WithZeroLimit sets how Run treats m <= 0. ZeroLimitReject is used by default.
+func WithZeroLimit(policy ZeroLimitPolicy) Option {
+	return func(o *options) {
+		o.zeroLimit = policy
+	}
+}
+
+// WithIgnoreErrors makes Run run all tasks regardless of their errors. m is not used.
+func WithIgnoreErrors() Option {
+	return func(o *options) {
+		o.ignoreErrors = true
+	}
+}
+
+// WithErrorRatio makes Run stop when more than ratio (0..1) of all tasks fail. m is not used.
+func WithErrorRatio(ratio float64) Option {
+	return func(o *options) {
+		o.useRatio = true
+		o.errorRatio = math.Max(ratio, 0)
+	}
+}
+
+// errorsLimit returns the number of errors which stops Run or 0 if errors are ignored.
+func (o *options) errorsLimit(tasksCount, m int) (int, error) {
+	switch {
+	case o.ignoreErrors:
+		return 0, nil
+	case o.useRatio:
+		// The product may be rounded below the exact value, 0.29*100 is 28.999...
+		return int(math.Floor(o.errorRatio*float64(tasksCount)+ratioTolerance)) + 1, nil
+	case m > 0:
+		return m, nil
+	}
+
+	switch o.zeroLimit {
+	case ZeroLimitIgnoreErrors:
+		return 0, nil
+	case ZeroLimitStopOnError:
+		return 1, nil
+	case ZeroLimitReject:
+	}
+	return 0, ErrErrorsLimitExceeded
+}
+
+// WithRetry makes Run retry a failed task up to retries times. The delay before
+// a retry starts from baseDelay and doubles up to maxDelay. Only the error of the
+// last attempt is counted towards the limit. Panics are not retried.
+func WithRetry(retries int, baseDelay, maxDelay time.Duration) Option {
+	return func(o *options) {
+		o.retries = retries
+		o.baseDelay = baseDelay
+		o.maxDelay = maxDelay
+	}
+}
+
+// WithJitter sets the random fraction (0..1) the retry delay can be reduced by.
+func WithJitter(jitter float64) Option {
+	return func(o *options) {
+		o.jitter = jitter
+	}
+}
+
+// WithRateLimit limits task starts (including retries) to rate per second with bursts up to burst.
+func WithRateLimit(rate float64, burst int) Option {
+	return func(o *options) {
+		o.rate = rate
+		o.burst = burst
+	}
+}
+
+// WithClock sets the clock used for retry delays and rate limiting.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithObserver adds the observer notified about the progress of Run or RunDAG.
+func WithObserver(observer Observer) Option {
+	return func(o *options) {
+		o.observer = append(o.observer, observer)
+	}
+}
+
+// executor runs a single task according to the options.
+type executor struct {
+	options
+	limiter *tokenBucket
+}
+
+func newExecutor(opts []Option) *executor {
+	e := &executor{options: options{jitter: DefaultJitter, clock: realClock{}}}
+	for _, opt := range opts {
+		opt(&e.options)
+	}
+	if e.rate > 0 {
+		e.limiter = newTokenBucket(e.clock, e.rate, e.burst)
+	}
+	return e
+}
+
+// execute runs the task with retries. The task is abandoned when stop is closed,
+// errNotStarted is returned if it happens before the first attempt. started is
+// called right before the first attempt.
+func (e *executor) execute(task Task, stop <-chan struct{}, started func()) error {
+	var err error
+	for attempt := 0; attempt <= e.retries; attempt++ {
+		if attempt > 0 && !sleep(e.clock, backoff(attempt, e.baseDelay, e.maxDelay, e.jitter), stop) {
+			return err
+		}
+		if e.limiter != nil && !e.limiter.wait(stop) {
+			if attempt == 0 {
+				return errNotStarted
+			}
+			return err
+		}
+		if attempt == 0 {
+			started()
+		}
+		err = safeRun(task)
+		var panicErr *PanicError
+		if err == nil || errors.As(err, &panicErr) {
+			return err
+		}
+	}
+	return err
+}
+
+// safeRun runs the task and converts its panic into PanicError.
+func safeRun(task Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return task()
+}
+
 // Run starts tasks in n goroutines and stops its work when receiving m errors from tasks.
-func Run(tasks []Task, n, m int) error {
-	if m == 0 {
-		return ErrErrorsLimitExceeded
+// Options may change the way errors are limited.
+func Run(tasks []Task, n, m int, opts ...Option) error {
+	_, err := RunWithSummary(tasks, n, m, opts...)
+	return err
+}
+
+// RunWithSummary works as Run and also returns the summary of the run.
+func RunWithSummary(tasks []Task, n, m int, opts ...Option) (Summary, error) {
+	e := newExecutor(opts)
+	start := e.clock.Now()
+	limit, err := e.errorsLimit(len(tasks), m)
+	if err != nil {
+		e.observer.OnLimitReached(0)
+		e.observer.OnCancel(len(tasks))
+		return Summary{Skipped: len(tasks)}, err
 	}
+
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	var stopOnce sync.Once
 
 	var errorsLimit int
+	var summary Summary
+	var panicErr *PanicError
 
-	chTasks := make(chan Task)
+	chTasks := make(chan indexedTask)
 	stop := make(chan struct{})
+	stopWorkers := func() {
+		stopOnce.Do(
+			func() {
+				close(stop)
+			},
+		)
+	}
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		defer close(chTasks)
-		for _, task := range tasks {
+		for i, task := range tasks {
 			select {
 			case <-stop:
 				return
-			case chTasks <- task:
+			case chTasks <- indexedTask{index: i, task: task}:
 			}
 		}
 	}()
@@ -40,20 +282,37 @@ func Run(tasks []Task, n, m int) error {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for task := range chTasks {
-				err := task()
-				if err != nil {
-					mu.Lock()
+			for t := range chTasks {
+				var taskStart time.Time
+				err := e.execute(t.task, stop, func() {
+					e.observer.OnTaskStart(t.index)
+					taskStart = e.clock.Now()
+				})
+				if errors.Is(err, errNotStarted) {
+					return
+				}
+				e.observer.OnTaskFinish(t.index, e.clock.Now().Sub(taskStart), err)
+
+				mu.Lock()
+				if err == nil {
+					summary.Succeeded++
+				} else {
+					summary.Failed++
+					var pErr *PanicError
+					if e.repanic && errors.As(err, &pErr) {
+						if panicErr == nil {
+							panicErr = pErr
+						}
+						stopWorkers()
+					}
 					errorsLimit++
-					if errorsLimit >= m {
-						stopOnce.Do(
-							func() {
-								close(stop)
-							},
-						)
+					if limit > 0 && errorsLimit == limit {
+						e.observer.OnLimitReached(errorsLimit)
+						stopWorkers()
 					}
-					mu.Unlock()
 				}
+				mu.Unlock()
+
 				select {
 				case <-stop:
 					return
@@ -63,8 +322,18 @@ func Run(tasks []Task, n, m int) error {
 		}()
 	}
 	wg.Wait()
-	if errorsLimit >= m {
-		return ErrErrorsLimitExceeded
+
+	summary.Skipped = len(tasks) - summary.Succeeded - summary.Failed
+	summary.WallTime = e.clock.Now().Sub(start)
+	if summary.Skipped > 0 {
+		e.observer.OnCancel(summary.Skipped)
+	}
+
+	if panicErr != nil {
+		panic(panicErr)
+	}
+	if limit > 0 && errorsLimit >= limit {
+		return summary, ErrErrorsLimitExceeded
 	}
-	return nil
+	return summary, nil
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -12,6 +13,72 @@ import (
 	"go.uber.org/goleak"
 )
 
+// fakeClock is a manually advanced Clock.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	delays  []time.Duration
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.delays = append(c.delays, d)
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward and fires the expired waiters.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+// Waiters returns the number of pending After calls.
+func (c *fakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// Delays returns the durations passed to After.
+func (c *fakeClock) Delays() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.delays...)
+}
+
 func TestRun(t *testing.T) {
 	defer goleak.VerifyNone(t)
 
@@ -109,3 +176,390 @@ func TestRun(t *testing.T) {
 		require.Truef(t, errors.Is(err, ErrErrorsLimitExceeded), "actual err - %v", err)
 	})
 }
+
+func TestRunPanics(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("panics are counted as errors", func(t *testing.T) {
+		tasksCount := 50
+		tasks := make([]Task, 0, tasksCount)
+
+		var runTasksCount int32
+
+		for i := 0; i < tasksCount; i++ {
+			tasks = append(tasks, func() error {
+				atomic.AddInt32(&runTasksCount, 1)
+				panic(fmt.Sprintf("panic from task %d", i))
+			})
+		}
+
+		workersCount := 5
+		maxErrorsCount := 10
+		err := Run(tasks, workersCount, maxErrorsCount)
+
+		require.Truef(t, errors.Is(err, ErrErrorsLimitExceeded), "actual err - %v", err)
+		require.LessOrEqual(t, runTasksCount, int32(workersCount+maxErrorsCount), "extra tasks were started")
+	})
+
+	t.Run("panics below the limit", func(t *testing.T) {
+		tasksCount := 50
+		tasks := make([]Task, 0, tasksCount)
+
+		var runTasksCount int32
+
+		for i := 0; i < tasksCount; i++ {
+			tasks = append(tasks, func() error {
+				atomic.AddInt32(&runTasksCount, 1)
+				if i%10 == 0 {
+					panic("unexpected")
+				}
+				return nil
+			})
+		}
+
+		err := Run(tasks, 5, 10)
+
+		require.NoError(t, err)
+		require.Equal(t, int32(tasksCount), runTasksCount, "not all tasks were completed")
+	})
+
+	t.Run("panic error keeps value and stack", func(t *testing.T) {
+		err := safeRun(func() error {
+			panic("boom")
+		})
+
+		var panicErr *PanicError
+		require.ErrorAs(t, err, &panicErr)
+		require.ErrorIs(t, err, ErrTaskPanicked)
+		require.Equal(t, "boom", panicErr.Value)
+		require.Contains(t, string(panicErr.Stack), "safeRun")
+	})
+
+	t.Run("repanic", func(t *testing.T) {
+		tasksCount := 50
+		tasks := make([]Task, 0, tasksCount)
+
+		var runTasksCount int32
+
+		for i := 0; i < tasksCount; i++ {
+			tasks = append(tasks, func() error {
+				atomic.AddInt32(&runTasksCount, 1)
+				if i == 3 {
+					panic("fail fast")
+				}
+				return nil
+			})
+		}
+
+		defer func() {
+			r := recover()
+			require.NotNil(t, r, "Run did not re-panic")
+
+			panicErr, ok := r.(*PanicError)
+			require.True(t, ok, "unexpected panic value - %v", r)
+			require.Equal(t, "fail fast", panicErr.Value)
+			require.Less(t, runTasksCount, int32(tasksCount), "tasks were not stopped")
+		}()
+
+		_ = Run(tasks, 1, 100, WithRepanic())
+	})
+}
+
+func TestRunRetry(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("flaky tasks succeed after retries", func(t *testing.T) {
+		tasksCount := 20
+		tasks := make([]Task, 0, tasksCount)
+
+		var runTasksCount int32
+
+		for i := 0; i < tasksCount; i++ {
+			var attempts int32
+			tasks = append(tasks, func() error {
+				atomic.AddInt32(&runTasksCount, 1)
+				if atomic.AddInt32(&attempts, 1) < 3 {
+					return fmt.Errorf("flaky error from task %d", i)
+				}
+				return nil
+			})
+		}
+
+		clock := newFakeClock()
+		done := make(chan error)
+		go func() {
+			done <- Run(tasks, 5, 1, WithRetry(2, 10*time.Millisecond, time.Second), WithClock(clock))
+		}()
+
+		var err error
+	loop:
+		for {
+			select {
+			case err = <-done:
+				break loop
+			default:
+				clock.Advance(time.Second)
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		require.NoError(t, err)
+		require.Equal(t, int32(tasksCount*3), runTasksCount)
+	})
+
+	t.Run("only final failures are counted", func(t *testing.T) {
+		tasksCount := 10
+		tasks := make([]Task, 0, tasksCount)
+
+		var runTasksCount int32
+
+		for i := 0; i < tasksCount; i++ {
+			tasks = append(tasks, func() error {
+				atomic.AddInt32(&runTasksCount, 1)
+				return fmt.Errorf("error from task %d", i)
+			})
+		}
+
+		clock := newFakeClock()
+		done := make(chan error)
+		go func() {
+			done <- Run(tasks, 1, 2, WithRetry(3, time.Millisecond, time.Millisecond), WithClock(clock))
+		}()
+
+		var err error
+	loop:
+		for {
+			select {
+			case err = <-done:
+				break loop
+			default:
+				clock.Advance(time.Millisecond)
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		require.Truef(t, errors.Is(err, ErrErrorsLimitExceeded), "actual err - %v", err)
+		require.Equal(t, int32(2*4), runTasksCount, "one worker must stop after two tasks with four attempts")
+	})
+
+	t.Run("exponential backoff", func(t *testing.T) {
+		clock := newFakeClock()
+		tasks := []Task{func() error { return errors.New("always") }}
+
+		done := make(chan error)
+		go func() {
+			done <- Run(tasks, 1, 1,
+				WithRetry(5, 10*time.Millisecond, 50*time.Millisecond),
+				WithJitter(0),
+				WithClock(clock),
+			)
+		}()
+
+		for i := 0; i < 5; i++ {
+			require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+			clock.Advance(50 * time.Millisecond)
+		}
+		require.ErrorIs(t, <-done, ErrErrorsLimitExceeded)
+
+		require.Equal(t, []time.Duration{
+			10 * time.Millisecond,
+			20 * time.Millisecond,
+			40 * time.Millisecond,
+			50 * time.Millisecond,
+			50 * time.Millisecond,
+		}, clock.Delays())
+	})
+
+	t.Run("jitter reduces delay", func(t *testing.T) {
+		for i := 1; i < 100; i++ {
+			delay := backoff(3, 10*time.Millisecond, time.Second, 0.5)
+			require.GreaterOrEqual(t, delay, 20*time.Millisecond)
+			require.LessOrEqual(t, delay, 40*time.Millisecond)
+		}
+	})
+}
+
+func TestRunRateLimit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tasksCount := 5
+	tasks := make([]Task, 0, tasksCount)
+
+	var runTasksCount int32
+
+	for i := 0; i < tasksCount; i++ {
+		tasks = append(tasks, func() error {
+			atomic.AddInt32(&runTasksCount, 1)
+			return nil
+		})
+	}
+
+	clock := newFakeClock()
+	done := make(chan error)
+	go func() {
+		done <- Run(tasks, tasksCount, 1, WithRateLimit(10, 2), WithClock(clock))
+	}()
+
+	started := func(count int32) func() bool {
+		return func() bool { return atomic.LoadInt32(&runTasksCount) == count }
+	}
+
+	// Burst is available at once, the rest of the tasks start one per 100ms.
+	require.Eventually(t, started(2), time.Second, time.Millisecond)
+	for count := int32(3); count <= int32(tasksCount); count++ {
+		require.Eventually(t, func() bool { return clock.Waiters() == tasksCount-int(count)+1 },
+			time.Second, time.Millisecond)
+		require.Never(t, started(count), 10*time.Millisecond, time.Millisecond)
+		clock.Advance(100 * time.Millisecond)
+		require.Eventually(t, started(count), time.Second, time.Millisecond)
+	}
+	require.NoError(t, <-done)
+}
+
+func TestRunRateLimitStopped(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tasksCount := 6
+	tasks := make([]Task, 0, tasksCount)
+
+	var runTasksCount int32
+
+	for i := 0; i < tasksCount; i++ {
+		tasks = append(tasks, func() error {
+			atomic.AddInt32(&runTasksCount, 1)
+			return fmt.Errorf("error from task %d", i)
+		})
+	}
+
+	// The only token is taken by the failed task, the waiting ones never start.
+	observer := newRecordingObserver()
+	summary, err := RunWithSummary(tasks, 2, 1, WithRateLimit(0.1, 1), WithClock(newFakeClock()), WithObserver(observer))
+	require.ErrorIs(t, err, ErrErrorsLimitExceeded)
+	require.Equal(t, int32(1), runTasksCount)
+	require.Equal(t, Summary{Failed: 1, Skipped: tasksCount - 1}, summary)
+	require.Len(t, observer.started, 1)
+	require.Len(t, observer.finished, 1)
+	require.Equal(t, tasksCount-1, observer.canceled)
+}
+
+func TestRunErrorsLimit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	// makeTasks returns tasksCount tasks where the first errorsCount fail.
+	makeTasks := func(tasksCount, errorsCount int, runTasksCount *int32) []Task {
+		tasks := make([]Task, 0, tasksCount)
+		for i := 0; i < tasksCount; i++ {
+			tasks = append(tasks, func() error {
+				atomic.AddInt32(runTasksCount, 1)
+				if i < errorsCount {
+					return fmt.Errorf("error from task %d", i)
+				}
+				return nil
+			})
+		}
+		return tasks
+	}
+
+	tests := []struct {
+		name        string
+		errorsCount int
+		m           int
+		opts        []Option
+		expectedErr error
+		allRun      bool
+	}{
+		{
+			name:        "ratio is not exceeded",
+			errorsCount: 5,
+			m:           1,
+			opts:        []Option{WithErrorRatio(0.1)},
+			allRun:      true,
+		},
+		{
+			name:        "ratio is exceeded",
+			errorsCount: 6,
+			m:           100,
+			opts:        []Option{WithErrorRatio(0.1)},
+			expectedErr: ErrErrorsLimitExceeded,
+		},
+		{
+			name:        "ratio boundary is not exceeded",
+			errorsCount: 29,
+			m:           1,
+			opts:        []Option{WithErrorRatio(0.58)},
+			allRun:      true,
+		},
+		{
+			name:        "ratio boundary is exceeded",
+			errorsCount: 30,
+			m:           1,
+			opts:        []Option{WithErrorRatio(0.58)},
+			expectedErr: ErrErrorsLimitExceeded,
+		},
+		{
+			name:        "zero ratio",
+			errorsCount: 1,
+			m:           100,
+			opts:        []Option{WithErrorRatio(0)},
+			expectedErr: ErrErrorsLimitExceeded,
+		},
+		{
+			name:        "ignore errors",
+			errorsCount: 50,
+			m:           1,
+			opts:        []Option{WithIgnoreErrors()},
+			allRun:      true,
+		},
+		{
+			name:        "zero limit rejects by default",
+			errorsCount: 0,
+			m:           0,
+			expectedErr: ErrErrorsLimitExceeded,
+		},
+		{
+			name:        "negative limit rejects by default",
+			errorsCount: 0,
+			m:           -1,
+			expectedErr: ErrErrorsLimitExceeded,
+		},
+		{
+			name:        "zero limit ignores errors",
+			errorsCount: 50,
+			m:           0,
+			opts:        []Option{WithZeroLimit(ZeroLimitIgnoreErrors)},
+			allRun:      true,
+		},
+		{
+			name:        "zero limit without errors",
+			errorsCount: 0,
+			m:           0,
+			opts:        []Option{WithZeroLimit(ZeroLimitStopOnError)},
+			allRun:      true,
+		},
+		{
+			name:        "zero limit stops on error",
+			errorsCount: 1,
+			m:           0,
+			opts:        []Option{WithZeroLimit(ZeroLimitStopOnError)},
+			expectedErr: ErrErrorsLimitExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasksCount := 50
+			var runTasksCount int32
+
+			err := Run(makeTasks(tasksCount, tt.errorsCount, &runTasksCount), 5, tt.m, tt.opts...)
+
+			if tt.expectedErr != nil {
+				require.Truef(t, errors.Is(err, tt.expectedErr), "actual err - %v", err)
+			} else {
+				require.NoError(t, err)
+			}
+			if tt.allRun {
+				require.Equal(t, int32(tasksCount), runTasksCount, "not all tasks were completed")
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package hw06pipelineexecution
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestPipelineBuffer(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	data := make([]interface{}, 0, 100)
+	for i := 0; i < 100; i++ {
+		data = append(data, i)
+	}
+	release := func(release chan struct{}) Stage {
+		return Map(func(v interface{}) interface{} {
+			<-release
+			return v
+		})
+	}
+
+	// hold closes held when the stage takes the first value.
+	hold := func(held, release chan struct{}) Stage {
+		first := true
+		return Map(func(v interface{}) interface{} {
+			if first {
+				first = false
+				close(held)
+			}
+			<-release
+			return v
+		})
+	}
+
+	t.Run("buffered channels keep all values", func(t *testing.T) {
+		out := ExecutePipelineWithOptions(sliceSource(data...), nil,
+			[]Option{WithBuffer(10, Block)},
+			Map(func(v interface{}) interface{} { return v }),
+			Map(func(v interface{}) interface{} { return v }),
+		)
+		require.Equal(t, data, readAll(out))
+	})
+
+	t.Run("buffer absorbs burst", func(t *testing.T) {
+		metrics := NewMetrics()
+		ch := make(chan struct{})
+		out := ExecutePipelineWithOptions(sliceSource(data...), nil,
+			[]Option{WithStageBuffer(1, 50, Block), WithMetrics(metrics)},
+			Map(func(v interface{}) interface{} { return v }),
+			release(ch),
+		)
+
+		// The fast stage is not stalled by the blocked one until the buffer is full.
+		require.Eventually(t, func() bool {
+			return metrics.Snapshot()[1].Queued == 50
+		}, time.Second, time.Millisecond)
+		require.GreaterOrEqual(t, metrics.Snapshot()[0].Out, int64(50))
+
+		close(ch)
+		require.Equal(t, data, readAll(out))
+	})
+
+	t.Run("drop newest", func(t *testing.T) {
+		metrics := NewMetrics()
+		ch := make(chan struct{})
+		held := make(chan struct{})
+		out := ExecutePipelineWithOptions(gatedSource(held, data...), nil,
+			[]Option{WithStageBuffer(0, 5, DropNewest), WithMetrics(metrics)},
+			hold(held, ch),
+		)
+
+		require.Eventually(t, func() bool {
+			snapshot := metrics.Snapshot()[0]
+			return snapshot.In+snapshot.Dropped == int64(len(data))
+		}, time.Second, time.Millisecond)
+		close(ch)
+
+		// The stage holds the first value, the buffer keeps the next ones.
+		require.Equal(t, data[:6], readAll(out))
+		require.Equal(t, int64(len(data)-6), metrics.Snapshot()[0].Dropped)
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		metrics := NewMetrics()
+		ch := make(chan struct{})
+		held := make(chan struct{})
+		out := ExecutePipelineWithOptions(gatedSource(held, data...), nil,
+			[]Option{WithStageBuffer(0, 5, DropOldest), WithMetrics(metrics)},
+			hold(held, ch),
+		)
+
+		require.Eventually(t, func() bool {
+			snapshot := metrics.Snapshot()[0]
+			return snapshot.In-snapshot.Out == 6 && snapshot.In+snapshot.Dropped == int64(len(data))
+		}, time.Second, time.Millisecond)
+		close(ch)
+
+		// The stage holds the first value, the buffer keeps the latest ones.
+		expected := append([]interface{}{data[0]}, data[len(data)-5:]...)
+		require.Equal(t, expected, readAll(out))
+		require.Equal(t, int64(len(data)-6), metrics.Snapshot()[0].Dropped)
+	})
+
+	t.Run("unbuffered drop", func(t *testing.T) {
+		metrics := NewMetrics()
+		ch := make(chan struct{})
+		held := make(chan struct{})
+		sent := make(chan int64, 1)
+		// Without a buffer the value is dropped unless the stage waits for it,
+		// so the first value is resent until the stage takes it.
+		src := make(Bi)
+		go func() {
+			defer close(src)
+			var n int64
+			for taken := false; !taken; {
+				select {
+				case <-held:
+					taken = true
+				case src <- data[0]:
+					n++
+				}
+			}
+			for _, v := range data[1:] {
+				src <- v
+				n++
+			}
+			sent <- n
+		}()
+		out := ExecutePipelineWithOptions(src, nil,
+			[]Option{WithBuffer(0, DropOldest), WithStageBuffer(1, 0, Block), WithMetrics(metrics)},
+			hold(held, ch),
+		)
+
+		n := <-sent
+		require.Eventually(t, func() bool {
+			snapshot := metrics.Snapshot()[0]
+			return snapshot.In+snapshot.Dropped == n
+		}, time.Second, time.Millisecond)
+		close(ch)
+
+		// There is no older value to drop, so the values sent while the stage is busy are lost.
+		require.Equal(t, data[:1], readAll(out))
+		require.Equal(t, int64(1), metrics.Snapshot()[0].In)
+	})
+
+	t.Run("done stops buffered pipeline", func(t *testing.T) {
+		done := make(Bi)
+		go func() {
+			<-time.After(sleepPerStage)
+			close(done)
+		}()
+
+		for name, policy := range map[string]OverflowPolicy{"block": Block, "newest": DropNewest, "oldest": DropOldest} {
+			t.Run(name, func(t *testing.T) {
+				start := time.Now()
+				out := ExecutePipelineWithOptions(endlessSource(done), done,
+					[]Option{WithBuffer(16, policy)},
+					Map(func(v interface{}) interface{} { return strconv.Itoa(v.(int)) }),
+					Map(func(v interface{}) interface{} {
+						time.Sleep(time.Millisecond)
+						return v
+					}),
+				)
+				readAll(out)
+				require.Less(t, int64(time.Since(start)), int64(sleepPerStage)+int64(fault))
+			})
+		}
+	})
+}
+
+// gatedSource sends the first value, waits for held to be closed and sends the rest of the values.
+func gatedSource(held <-chan struct{}, values ...interface{}) In {
+	in := make(Bi)
+	go func() {
+		defer close(in)
+		in <- values[0]
+		<-held
+		for _, v := range values[1:] {
+			in <- v
+		}
+	}()
+	return in
+}
+
+// burstyStage sleeps on every tenth value starting from the shift.
+func burstyStage(shift int) Stage {
+	return Map(func(v interface{}) interface{} {
+		if (v.(int)+shift)%10 == 0 {
+			time.Sleep(100 * time.Microsecond)
+		}
+		return v
+	})
+}
+
+func benchmarkPipeline(b *testing.B, opts ...Option) {
+	b.Helper()
+	in := make(Bi)
+	go func() {
+		defer close(in)
+		for i := 0; i < b.N; i++ {
+			in <- i
+		}
+	}()
+
+	b.ResetTimer()
+	received := 0
+	for range ExecutePipelineWithOptions(in, nil, opts, burstyStage(0), burstyStage(3), burstyStage(6)) {
+		received++
+	}
+	b.ReportMetric(float64(received)/float64(b.N), "delivered/op")
+}
+
+func BenchmarkPipelineBuffer(b *testing.B) {
+	b.Run("unbuffered", func(b *testing.B) {
+		benchmarkPipeline(b)
+	})
+	for _, size := range []int{1, 16, 128} {
+		b.Run("block "+strconv.Itoa(size), func(b *testing.B) {
+			benchmarkPipeline(b, WithBuffer(size, Block))
+		})
+	}
+	b.Run("drop newest 16", func(b *testing.B) {
+		benchmarkPipeline(b, WithBuffer(16, DropNewest))
+	})
+	b.Run("drop oldest 16", func(b *testing.B) {
+		benchmarkPipeline(b, WithBuffer(16, DropOldest))
+	})
+}
@@ -0,0 +1,349 @@
+package hw06pipelineexecution
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the stage latency histogram.
+var DefaultLatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// MaxPendingLatencies is the number of values waiting in a stage for their output
+// above which the stage is not considered to emit one value per input.
+const MaxPendingLatencies = 4096
+
+// Metrics collects per-stage statistics of a pipeline run.
+// Stage latency is the time between a value entering the stage and the next
+// value leaving it, which is exact for stages emitting one value per input in order.
+// Latency is unavailable for other stages (Filter, FlatMap, Batch, windows): it is
+// reset and no longer recorded once the stage emits more values than it received
+// or more than MaxPendingLatencies values wait for their output.
+type Metrics struct {
+	names   []string
+	buckets []time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	started time.Time
+	stages  []*stageMetrics
+}
+
+// NewMetrics creates metrics with optional stage names used in the report.
+func NewMetrics(names ...string) *Metrics {
+	return &Metrics{
+		names:   names,
+		buckets: DefaultLatencyBuckets,
+		now:     time.Now,
+	}
+}
+
+// stageMetrics counts values passing through a single stage.
+type stageMetrics struct {
+	m *Metrics
+
+	mu       sync.Mutex
+	in       int64
+	out      int64
+	dropped  int64
+	queued   func() int
+	entered  []time.Time
+	unpaired bool
+	counts   []int64
+	overflow int64
+	observed int64
+	sum      time.Duration
+	min      time.Duration
+	max      time.Duration
+}
+
+// LatencySnapshot describes the processing latency of a stage.
+// Percentiles are upper bounds of the histogram buckets they fall into.
+type LatencySnapshot struct {
+	Count   int64
+	Min     time.Duration
+	Max     time.Duration
+	Mean    time.Duration
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Buckets []BucketSnapshot
+}
+
+// BucketSnapshot is the number of latencies not greater than UpperBound.
+type BucketSnapshot struct {
+	UpperBound time.Duration
+	Count      int64
+}
+
+// StageSnapshot is the state of a stage at the moment of the snapshot.
+// Backlog is the number of values given to the stage and not emitted yet,
+// Queued is the part of them waiting in the input buffer, Dropped is the number
+// of values lost because of the overflow policy, Throughput is the number of
+// emitted values per second since the pipeline start.
+type StageSnapshot struct {
+	Name       string
+	In         int64
+	Out        int64
+	Backlog    int64
+	Queued     int
+	Dropped    int64
+	Throughput float64
+	Latency    LatencySnapshot
+}
+
+// start resets the metrics for a pipeline of stagesCount stages.
+func (m *Metrics) start(stagesCount int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.started = m.now()
+	m.stages = make([]*stageMetrics, 0, stagesCount)
+	for i := 0; i < stagesCount; i++ {
+		m.stages = append(m.stages, &stageMetrics{m: m, counts: make([]int64, len(m.buckets))})
+	}
+}
+
+// stage returns the metrics of the stage or nil if m is nil.
+func (m *Metrics) stage(i int) *stageMetrics {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stages[i]
+}
+
+// entering counts the value about to enter the stage.
+func (s *stageMetrics) entering() {
+	if s == nil {
+		return
+	}
+	now := s.m.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enter(now)
+}
+
+// tryEnter counts the value if send succeeds. The counting is atomic with
+// the send, so the stage cannot emit the value before it is counted.
+func (s *stageMetrics) tryEnter(send func() bool) bool {
+	if s == nil {
+		return send()
+	}
+	now := s.m.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !send() {
+		return false
+	}
+	s.enter(now)
+	return true
+}
+
+// enter records the value entering the stage. Must be called with s.mu held.
+func (s *stageMetrics) enter(now time.Time) {
+	s.in++
+	if s.unpaired {
+		return
+	}
+	if len(s.entered) == MaxPendingLatencies {
+		s.unpair()
+		return
+	}
+	s.entered = append(s.entered, now)
+}
+
+// unpair stops measuring latency of the stage which does not emit one value per input.
+// Must be called with s.mu held.
+func (s *stageMetrics) unpair() {
+	s.unpaired = true
+	s.entered = nil
+	s.counts = make([]int64, len(s.counts))
+	s.overflow = 0
+	s.observed = 0
+	s.sum = 0
+	s.min = 0
+	s.max = 0
+}
+
+// drop counts the value dropped before entering the stage.
+func (s *stageMetrics) drop() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropped++
+}
+
+// evict counts the value removed from the stage input buffer which still holds queued values.
+func (s *stageMetrics) evict(queued int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropped++
+	s.in--
+	// Entries are ordered: values held by the stage, then the buffered ones.
+	if i := len(s.entered) - queued - 1; i >= 0 {
+		s.entered = append(s.entered[:i], s.entered[i+1:]...)
+	}
+}
+
+// leave counts the value emitted by the stage.
+func (s *stageMetrics) leave() {
+	if s == nil {
+		return
+	}
+	now := s.m.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.out++
+	if s.unpaired {
+		return
+	}
+	if len(s.entered) == 0 {
+		s.unpair()
+		return
+	}
+	s.observe(now.Sub(s.entered[0]))
+	s.entered = s.entered[1:]
+}
+
+// watchQueue sets the function returning the number of values buffered at the stage input.
+func (s *stageMetrics) watchQueue(queued func() int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queued = queued
+}
+
+// observe adds the latency to the histogram. Must be called with s.mu held.
+func (s *stageMetrics) observe(latency time.Duration) {
+	s.observed++
+	if s.observed == 1 || latency < s.min {
+		s.min = latency
+	}
+	if latency > s.max {
+		s.max = latency
+	}
+	s.sum += latency
+	for i, bound := range s.m.buckets {
+		if latency <= bound {
+			s.counts[i]++
+			return
+		}
+	}
+	s.overflow++
+}
+
+// Snapshot returns the current state of all stages.
+func (m *Metrics) Snapshot() []StageSnapshot {
+	m.mu.Lock()
+	stages := m.stages
+	elapsed := m.now().Sub(m.started).Seconds()
+	m.mu.Unlock()
+
+	snapshots := make([]StageSnapshot, 0, len(stages))
+	for i, s := range stages {
+		name := fmt.Sprintf("stage %d", i)
+		if i < len(m.names) {
+			name = m.names[i]
+		}
+		snapshots = append(snapshots, s.snapshot(name, elapsed))
+	}
+	return snapshots
+}
+
+func (s *stageMetrics) snapshot(name string, elapsed float64) StageSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := StageSnapshot{
+		Name:    name,
+		In:      s.in,
+		Out:     s.out,
+		Backlog: s.in - s.out,
+		Dropped: s.dropped,
+	}
+	if s.queued != nil {
+		snapshot.Queued = s.queued()
+	}
+	if elapsed > 0 {
+		snapshot.Throughput = float64(s.out) / elapsed
+	}
+	if snapshot.Backlog < 0 {
+		snapshot.Backlog = 0
+	}
+
+	count := s.observed
+	cumulative := int64(0)
+	buckets := make([]BucketSnapshot, 0, len(s.counts))
+	for i, c := range s.counts {
+		cumulative += c
+		buckets = append(buckets, BucketSnapshot{UpperBound: s.m.buckets[i], Count: cumulative})
+	}
+	snapshot.Latency = LatencySnapshot{
+		Count:   count,
+		Min:     s.min,
+		Max:     s.max,
+		Buckets: buckets,
+	}
+	if count > 0 {
+		snapshot.Latency.Mean = s.sum / time.Duration(count)
+		snapshot.Latency.P50 = s.percentile(buckets, count, 0.5)
+		snapshot.Latency.P90 = s.percentile(buckets, count, 0.9)
+		snapshot.Latency.P99 = s.percentile(buckets, count, 0.99)
+	}
+	return snapshot
+}
+
+// percentile returns the upper bound of the bucket containing the q-th latency,
+// or the maximum latency if it is beyond all buckets.
+func (s *stageMetrics) percentile(buckets []BucketSnapshot, count int64, q float64) time.Duration {
+	rank := int64(q * float64(count))
+	if rank < 1 {
+		rank = 1
+	}
+	for _, b := range buckets {
+		if b.Count >= rank {
+			return b.UpperBound
+		}
+	}
+	return s.max
+}
+
+// WriteReport writes the snapshot as a table.
+func (m *Metrics) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "stage\tin\tout\tbacklog\tqueued\tdropped\tthroughput/s\tmean\tp50\tp90\tp99\tmax\t")
+	for _, s := range m.Snapshot() {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%.1f\t%v\t%v\t%v\t%v\t%v\t\n",
+			s.Name, s.In, s.Out, s.Backlog, s.Queued, s.Dropped, s.Throughput,
+			s.Latency.Mean, s.Latency.P50, s.Latency.P90, s.Latency.P99, s.Latency.Max)
+	}
+	return tw.Flush()
+}
+
+// Report returns the snapshot as a printable table.
+func (m *Metrics) Report() string {
+	var sb strings.Builder
+	_ = m.WriteReport(&sb)
+	return sb.String()
+}
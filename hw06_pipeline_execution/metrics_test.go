@@ -0,0 +1,135 @@
+package hw06pipelineexecution
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMetrics(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	sleepy := func(d time.Duration) Stage {
+		return Map(func(v interface{}) interface{} {
+			time.Sleep(d)
+			return v
+		})
+	}
+
+	t.Run("per stage statistics", func(t *testing.T) {
+		metrics := NewMetrics("fast", "slow", "filter")
+		data := []interface{}{1, 2, 3, 4, 5}
+
+		out := ExecutePipelineWithOptions(sliceSource(data...), nil, []Option{WithMetrics(metrics)},
+			Map(func(v interface{}) interface{} { return v }),
+			sleepy(sleepPerStage/5),
+			Filter(func(v interface{}) bool { return v.(int)%2 == 1 }),
+		)
+		require.Len(t, readAll(out), 3)
+
+		snapshot := metrics.Snapshot()
+		require.Len(t, snapshot, 3)
+
+		fast, slow, filter := snapshot[0], snapshot[1], snapshot[2]
+		require.Equal(t, "fast", fast.Name)
+		require.Equal(t, int64(5), fast.In)
+		require.Equal(t, int64(5), fast.Out)
+		require.Zero(t, fast.Backlog)
+
+		require.Equal(t, int64(5), slow.In)
+		require.Equal(t, int64(5), slow.Out)
+		require.Equal(t, int64(5), slow.Latency.Count)
+		require.GreaterOrEqual(t, slow.Latency.Min, sleepPerStage/5)
+		require.GreaterOrEqual(t, slow.Latency.Mean, sleepPerStage/5)
+		require.Equal(t, 50*time.Millisecond, slow.Latency.P50)
+		require.Equal(t, int64(5), fast.Latency.Count)
+		require.Greater(t, slow.Throughput, 0.0)
+
+		require.Equal(t, int64(5), filter.In)
+		require.Equal(t, int64(3), filter.Out)
+
+		buckets := slow.Latency.Buckets
+		require.Len(t, buckets, len(DefaultLatencyBuckets))
+		require.Equal(t, int64(5), buckets[len(buckets)-1].Count)
+	})
+
+	t.Run("latency of not one to one stages", func(t *testing.T) {
+		metrics := NewMetrics("filter", "flatmap")
+		data := make([]interface{}, 0, 10000)
+		for i := 0; i < 10000; i++ {
+			data = append(data, i)
+		}
+
+		out := ExecutePipelineWithOptions(sliceSource(data...), nil, []Option{WithMetrics(metrics)},
+			Filter(func(v interface{}) bool { return v.(int)%1000 == 0 }),
+			FlatMap(func(v interface{}) []interface{} { return []interface{}{v, v} }),
+		)
+		require.Len(t, readAll(out), 20)
+
+		filter, flatMap := metrics.Snapshot()[0], metrics.Snapshot()[1]
+		require.Equal(t, int64(10000), filter.In)
+		require.Equal(t, int64(10), filter.Out)
+		require.Zero(t, filter.Latency.Count)
+		require.Equal(t, int64(10), flatMap.In)
+		require.Equal(t, int64(20), flatMap.Out)
+		require.Zero(t, flatMap.Latency.Count)
+		for _, stage := range metrics.stages {
+			require.LessOrEqual(t, len(stage.entered), MaxPendingLatencies)
+		}
+	})
+
+	t.Run("backlog", func(t *testing.T) {
+		metrics := NewMetrics()
+		release := make(chan struct{})
+		blocking := Map(func(v interface{}) interface{} {
+			<-release
+			return v
+		})
+
+		out := ExecutePipelineWithOptions(sliceSource(1, 2, 3), nil, []Option{WithMetrics(metrics)}, blocking)
+
+		// The first value is held by the stage, the second one waits to enter it.
+		require.Eventually(t, func() bool {
+			return metrics.Snapshot()[0].Backlog == 2
+		}, time.Second, time.Millisecond)
+		require.Zero(t, metrics.Snapshot()[0].Out)
+
+		close(release)
+		require.Len(t, readAll(out), 3)
+		require.Zero(t, metrics.Snapshot()[0].Backlog)
+	})
+
+	t.Run("report", func(t *testing.T) {
+		metrics := NewMetrics("parse")
+		out := ExecutePipelineWithOptions(sliceSource(1, 2), nil, []Option{WithMetrics(metrics)},
+			sleepy(time.Millisecond), sleepy(time.Millisecond))
+		readAll(out)
+
+		report := metrics.Report()
+		lines := strings.Split(strings.TrimSpace(report), "\n")
+		require.Len(t, lines, 3)
+		require.Contains(t, lines[0], "throughput/s")
+		require.Contains(t, lines[1], "parse")
+		require.Contains(t, lines[2], "stage 1")
+	})
+
+	t.Run("done stops instrumented pipeline", func(t *testing.T) {
+		metrics := NewMetrics()
+		done := make(Bi)
+		go func() {
+			<-time.After(sleepPerStage)
+			close(done)
+		}()
+
+		out := ExecutePipelineWithOptions(endlessSource(done), done, []Option{WithMetrics(metrics)},
+			sleepy(time.Millisecond), sleepy(2*time.Millisecond))
+		readAll(out)
+
+		snapshot := metrics.Snapshot()
+		require.Greater(t, snapshot[1].Out, int64(0))
+		require.LessOrEqual(t, snapshot[1].Out, snapshot[0].Out)
+	})
+}
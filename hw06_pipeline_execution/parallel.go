@@ -0,0 +1,101 @@
+package hw06pipelineexecution
+
+import "sync"
+
+// Order defines the order of values produced by a parallel stage.
+type Order int
+
+const (
+	// Ordered keeps the output in the order of the input.
+	Ordered Order = iota
+	// Unordered emits values as soon as they are processed.
+	Unordered
+)
+
+// ParallelStage creates a stage applying f to the input values in workers goroutines.
+func ParallelStage(workers int, order Order, f func(v interface{}) interface{}) Stage {
+	if workers < 1 {
+		workers = 1
+	}
+	return func(in In) Out {
+		if order == Ordered {
+			return parallelOrdered(in, workers, f)
+		}
+		return parallelUnordered(in, workers, f)
+	}
+}
+
+// FanOut runs workers copies of the stage reading the same input and merges their outputs.
+// The order of the output values is not preserved.
+func FanOut(stage Stage, workers int) Stage {
+	if workers < 1 {
+		workers = 1
+	}
+	return func(in In) Out {
+		outs := make([]Out, 0, workers)
+		for i := 0; i < workers; i++ {
+			outs = append(outs, stage(in))
+		}
+		return Merge(nil, outs...)
+	}
+}
+
+func parallelUnordered(in In, workers int, f func(v interface{}) interface{}) Out {
+	out := make(Bi)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range in {
+				out <- f(v)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+type orderedJob struct {
+	value  interface{}
+	result chan interface{}
+}
+
+// parallelOrdered processes values concurrently and emits the results in the input order.
+// Every value gets its own result channel, queued in the input order,
+// so no more than workers values are processed ahead of the emitted one.
+func parallelOrdered(in In, workers int, f func(v interface{}) interface{}) Out {
+	out := make(Bi)
+	jobs := make(chan orderedJob)
+	results := make(chan chan interface{}, workers)
+
+	go func() {
+		defer close(jobs)
+		defer close(results)
+		for v := range in {
+			result := make(chan interface{}, 1)
+			results <- result
+			jobs <- orderedJob{value: v, result: result}
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobs {
+				job.result <- f(job.value)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(out)
+		for result := range results {
+			out <- <-result
+		}
+	}()
+
+	return out
+}
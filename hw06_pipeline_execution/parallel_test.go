@@ -0,0 +1,168 @@
+package hw06pipelineexecution
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestParallelStage(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	workers := 5
+	slow := func(v interface{}) interface{} {
+		time.Sleep(sleepPerStage/2 + time.Millisecond*time.Duration(rand.Intn(int(sleepPerStage/time.Millisecond))))
+		return v.(int) * 2
+	}
+
+	source := func(data []int) In {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			for _, v := range data {
+				in <- v
+			}
+		}()
+		return in
+	}
+
+	data := make([]int, 0, 20)
+	expected := make([]int, 0, 20)
+	for i := 0; i < 20; i++ {
+		data = append(data, i)
+		expected = append(expected, i*2)
+	}
+
+	t.Run("ordered", func(t *testing.T) {
+		result := make([]int, 0, len(data))
+		start := time.Now()
+		for v := range ExecutePipeline(source(data), nil, ParallelStage(workers, Ordered, slow)) {
+			result = append(result, v.(int))
+		}
+		elapsed := time.Since(start)
+
+		require.Equal(t, expected, result)
+		// Sequential processing takes at least len(data) * sleepPerStage / 2.
+		require.Less(t, int64(elapsed), int64(sleepPerStage)*int64(len(data))/2)
+	})
+
+	t.Run("unordered", func(t *testing.T) {
+		result := make([]int, 0, len(data))
+		start := time.Now()
+		for v := range ExecutePipeline(source(data), nil, ParallelStage(workers, Unordered, slow)) {
+			result = append(result, v.(int))
+		}
+		elapsed := time.Since(start)
+
+		sort.Ints(result)
+		require.Equal(t, expected, result)
+		require.Less(t, int64(elapsed), int64(sleepPerStage)*int64(len(data))/2)
+	})
+
+	t.Run("fan out", func(t *testing.T) {
+		var running, maxRunning int32
+		stage := func(in In) Out {
+			out := make(Bi)
+			go func() {
+				defer close(out)
+				for v := range in {
+					current := atomic.AddInt32(&running, 1)
+					for {
+						prev := atomic.LoadInt32(&maxRunning)
+						if current <= prev || atomic.CompareAndSwapInt32(&maxRunning, prev, current) {
+							break
+						}
+					}
+					out <- slow(v)
+					atomic.AddInt32(&running, -1)
+				}
+			}()
+			return out
+		}
+
+		result := make([]int, 0, len(data))
+		for v := range ExecutePipeline(source(data), nil, FanOut(stage, workers)) {
+			result = append(result, v.(int))
+		}
+
+		sort.Ints(result)
+		require.Equal(t, expected, result)
+		require.LessOrEqual(t, maxRunning, int32(workers))
+		require.Greater(t, maxRunning, int32(1), "stage copies were run sequentially?")
+	})
+
+	t.Run("mixed with single stages", func(t *testing.T) {
+		stringifier := func(in In) Out {
+			out := make(Bi)
+			go func() {
+				defer close(out)
+				for v := range in {
+					out <- strconv.Itoa(v.(int))
+				}
+			}()
+			return out
+		}
+
+		result := make([]string, 0, len(data))
+		for v := range ExecutePipeline(source(data), nil,
+			ParallelStage(workers, Ordered, slow),
+			stringifier,
+			ParallelStage(workers, Ordered, func(v interface{}) interface{} { return v.(string) + "!" }),
+		) {
+			result = append(result, v.(string))
+		}
+
+		require.Len(t, result, len(data))
+		for i, s := range result {
+			require.Equal(t, strconv.Itoa(expected[i])+"!", s)
+		}
+	})
+
+	for name, order := range map[string]Order{"ordered": Ordered, "unordered": Unordered} {
+		t.Run("done case "+name, func(t *testing.T) {
+			done := make(Bi)
+			in := make(Bi)
+			var processed int32
+
+			// Infinite source stopped only by done.
+			go func() {
+				defer close(in)
+				for i := 0; ; i++ {
+					select {
+					case <-done:
+						return
+					case in <- i:
+					}
+				}
+			}()
+
+			abortDur := sleepPerStage * 2
+			go func() {
+				<-time.After(abortDur)
+				close(done)
+			}()
+
+			start := time.Now()
+			count := 0
+			for range ExecutePipeline(in, done,
+				ParallelStage(workers, order, func(v interface{}) interface{} {
+					atomic.AddInt32(&processed, 1)
+					return slow(v)
+				}),
+				FanOut(func(in In) Out { return ParallelStage(2, order, slow)(in) }, 2),
+			) {
+				count++
+			}
+			elapsed := time.Since(start)
+
+			require.Less(t, int64(elapsed), int64(abortDur)+int64(sleepPerStage)*2)
+			require.Less(t, count, int(atomic.LoadInt32(&processed))+1)
+		})
+	}
+}
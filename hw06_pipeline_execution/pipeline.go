@@ -8,7 +8,169 @@ type (
 
 type Stage func(in In) (out Out)
 
+// OverflowPolicy defines what happens to a value when the channel buffer is full.
+type OverflowPolicy int
+
+const (
+	// Block waits until there is room in the buffer.
+	Block OverflowPolicy = iota
+	// DropNewest drops the value which does not fit.
+	DropNewest
+	// DropOldest drops the oldest buffered value to make room for the new one.
+	DropOldest
+)
+
+// Option configures ExecutePipelineWithOptions.
+type Option func(*config)
+
+type config struct {
+	metrics *Metrics
+	buffer  buffer
+	buffers map[int]buffer
+}
+
+type buffer struct {
+	size   int
+	policy OverflowPolicy
+}
+
+// WithMetrics makes the pipeline record its statistics into m.
+func WithMetrics(m *Metrics) Option {
+	return func(c *config) {
+		c.metrics = m
+	}
+}
+
+// WithBuffer sets the buffer size and the overflow policy of all pipeline channels.
+func WithBuffer(size int, policy OverflowPolicy) Option {
+	return func(c *config) {
+		c.buffer = buffer{size: size, policy: policy}
+	}
+}
+
+// WithStageBuffer sets the buffer size and the overflow policy of the stage input channel.
+// The stage index equal to the number of stages refers to the pipeline output.
+func WithStageBuffer(stage, size int, policy OverflowPolicy) Option {
+	return func(c *config) {
+		if c.buffers == nil {
+			c.buffers = make(map[int]buffer)
+		}
+		c.buffers[stage] = buffer{size: size, policy: policy}
+	}
+}
+
 func ExecutePipeline(in In, done In, stages ...Stage) Out {
-	// Place your code here.
-	return nil
+	return ExecutePipelineWithOptions(in, done, nil, stages...)
+}
+
+// ExecutePipelineWithOptions works as ExecutePipeline configured by opts.
+func ExecutePipelineWithOptions(in In, done In, opts []Option, stages ...Stage) Out {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.metrics != nil {
+		c.metrics.start(len(stages))
+	}
+
+	out := in
+	var prev *stageMetrics
+	for i, stage := range stages {
+		next := c.metrics.stage(i)
+		out = stage(forward(done, out, link{buffer: c.bufferOf(i), from: prev, to: next}))
+		prev = next
+	}
+	return forward(done, out, link{buffer: c.bufferOf(len(stages)), from: prev})
+}
+
+// bufferOf returns the buffer of the stage input channel.
+func (c *config) bufferOf(stage int) buffer {
+	if b, ok := c.buffers[stage]; ok {
+		return b
+	}
+	return c.buffer
+}
+
+// link describes the channel between two stages.
+type link struct {
+	buffer
+	from *stageMetrics
+	to   *stageMetrics
+}
+
+// orDone forwards values from in until it is closed or done is closed.
+// After that in is drained to let the goroutines writing to it finish.
+func orDone[T any](done In, in <-chan T) <-chan T {
+	return forward(done, in, link{})
+}
+
+// forward works as orDone with the output buffered and counted according to the link.
+func forward[T any](done In, in <-chan T, l link) <-chan T {
+	size := l.size
+	if size < 0 {
+		size = 0
+	}
+	policy := l.policy
+	if size == 0 && policy == DropOldest {
+		// Without a buffer there is no older value to drop.
+		policy = DropNewest
+	}
+	out := make(chan T, size)
+	l.to.watchQueue(func() int { return len(out) })
+
+	trySend := func(v T) func() bool {
+		return func() bool {
+			select {
+			case out <- v:
+				return true
+			default:
+				return false
+			}
+		}
+	}
+
+	go func() {
+		defer func() {
+			close(out)
+			for range in { //nolint:revive
+			}
+		}()
+		for {
+			select {
+			case <-done:
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				l.from.leave()
+
+				switch policy {
+				case DropNewest:
+					if !l.to.tryEnter(trySend(v)) {
+						l.to.drop()
+					}
+					continue
+				case DropOldest:
+					for !l.to.tryEnter(trySend(v)) {
+						select {
+						case <-out:
+							l.to.evict(len(out))
+						default:
+						}
+					}
+					continue
+				case Block:
+				}
+
+				l.to.entering()
+				select {
+				case <-done:
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
 }
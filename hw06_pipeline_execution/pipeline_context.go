@@ -0,0 +1,127 @@
+package hw06pipelineexecution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrorPolicy defines what a context pipeline does with stage errors.
+type ErrorPolicy int
+
+const (
+	// Abort cancels the whole pipeline on the first error.
+	Abort ErrorPolicy = iota
+	// Skip drops the failed value and goes on.
+	Skip
+	// Collect drops the failed value, goes on and reports all errors in the end.
+	Collect
+)
+
+// ContextStage turns a value into a new one or fails.
+type ContextStage func(ctx context.Context, v interface{}) (interface{}, error)
+
+// StageError is an error returned by a stage of the context pipeline.
+type StageError struct {
+	Stage int
+	Value interface{}
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %d failed on %v: %v", e.Stage, e.Value, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// ExecutePipelineContext runs values from in through the stages until in is closed or ctx is canceled.
+// The output must be read until it is closed. The returned wait function blocks until all stages
+// stop and returns the final error: the first stage error for Abort, all of them for Collect,
+// nil for Skip, or the context error if ctx was canceled. After the pipeline is canceled
+// in is drained until it is closed, so its writer is not blocked.
+func ExecutePipelineContext(
+	ctx context.Context, in In, policy ErrorPolicy, stages ...ContextStage,
+) (Out, func() error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	var mu sync.Mutex
+	var errs []error
+	wg := sync.WaitGroup{}
+
+	report := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch policy {
+		case Abort:
+			cancel(err)
+		case Collect:
+			errs = append(errs, err)
+		case Skip:
+		}
+	}
+
+	out := in
+	for i, stage := range stages {
+		out = runContextStage(ctx, &wg, out, i, stage, report)
+	}
+
+	wait := func() error {
+		wg.Wait()
+		// The cause is either the aborting stage error or the parent context error.
+		cause := context.Cause(ctx)
+		cancel(nil)
+		if cause != nil {
+			return cause
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return errors.Join(errs...)
+	}
+	return out, wait
+}
+
+// runContextStage applies the stage to values from in in a new goroutine.
+func runContextStage(
+	ctx context.Context, wg *sync.WaitGroup, in In, index int, stage ContextStage, report func(error),
+) Out {
+	out := make(Bi)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				drain(in)
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				res, err := stage(ctx, v)
+				if err != nil {
+					report(&StageError{Stage: index, Value: v, Err: err})
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					drain(in)
+					return
+				case out <- res:
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// drain reads in until it is closed in a new goroutine, wait does not block on the writer.
+func drain(in In) {
+	go func() {
+		for range in { //nolint:revive
+		}
+	}()
+}
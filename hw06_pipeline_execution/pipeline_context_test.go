@@ -0,0 +1,149 @@
+package hw06pipelineexecution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestPipelineContext(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	errOdd := errors.New("odd value")
+
+	double := func(_ context.Context, v interface{}) (interface{}, error) {
+		return v.(int) * 2, nil
+	}
+	evenOnly := func(_ context.Context, v interface{}) (interface{}, error) {
+		if v.(int)%2 != 0 {
+			return nil, fmt.Errorf("%w: %d", errOdd, v)
+		}
+		return v, nil
+	}
+	addOne := func(_ context.Context, v interface{}) (interface{}, error) {
+		return v.(int) + 1, nil
+	}
+
+	// source sends data until it is over or ctx is canceled.
+	source := func(ctx context.Context, data []int) In {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			for _, v := range data {
+				select {
+				case <-ctx.Done():
+					return
+				case in <- v:
+				}
+			}
+		}()
+		return in
+	}
+
+	collect := func(out Out) []int {
+		result := make([]int, 0)
+		for v := range out {
+			result = append(result, v.(int))
+		}
+		return result
+	}
+
+	data := []int{1, 2, 3, 4, 5}
+
+	t.Run("without errors", func(t *testing.T) {
+		ctx := context.Background()
+		out, wait := ExecutePipelineContext(ctx, source(ctx, data), Abort, double, addOne)
+
+		require.Equal(t, []int{3, 5, 7, 9, 11}, collect(out))
+		require.NoError(t, wait())
+	})
+
+	t.Run("abort on first error", func(t *testing.T) {
+		many := make([]int, 1000)
+		for i := range many {
+			many[i] = i * 2
+		}
+		many[10] = 21
+
+		// The writer does not know the pipeline context, it is unblocked by draining.
+		in := make(Bi)
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+			defer close(in)
+			for _, v := range many {
+				in <- v
+			}
+		}()
+		out, wait := ExecutePipelineContext(context.Background(), in, Abort, evenOnly, double)
+
+		result := collect(out)
+		err := wait()
+
+		require.ErrorIs(t, err, errOdd)
+		var stageErr *StageError
+		require.ErrorAs(t, err, &stageErr)
+		require.Equal(t, 0, stageErr.Stage)
+		require.Equal(t, 21, stageErr.Value)
+		require.LessOrEqual(t, len(result), 10)
+		require.Eventually(t, func() bool {
+			select {
+			case <-finished:
+				return true
+			default:
+				return false
+			}
+		}, time.Second, time.Millisecond, "writer is blocked")
+	})
+
+	t.Run("skip failed values", func(t *testing.T) {
+		ctx := context.Background()
+		out, wait := ExecutePipelineContext(ctx, source(ctx, data), Skip, evenOnly, addOne)
+
+		require.Equal(t, []int{3, 5}, collect(out))
+		require.NoError(t, wait())
+	})
+
+	t.Run("collect errors", func(t *testing.T) {
+		ctx := context.Background()
+		out, wait := ExecutePipelineContext(ctx, source(ctx, data), Collect, addOne, evenOnly)
+
+		require.Equal(t, []int{2, 4, 6}, collect(out))
+		err := wait()
+		require.ErrorIs(t, err, errOdd)
+		require.EqualError(t, err, "stage 1 failed on 3: odd value: 3\nstage 1 failed on 5: odd value: 5")
+	})
+
+	t.Run("context cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		slow := func(ctx context.Context, v interface{}) (interface{}, error) {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(sleepPerStage):
+				return v, nil
+			}
+		}
+
+		go func() {
+			<-time.After(sleepPerStage * 2)
+			cancel()
+		}()
+
+		start := time.Now()
+		out, wait := ExecutePipelineContext(ctx, source(ctx, data), Collect, slow, slow, slow, slow)
+		result := collect(out)
+		elapsed := time.Since(start)
+
+		require.ErrorIs(t, wait(), context.Canceled)
+		require.Len(t, result, 0)
+		require.Less(t, int64(elapsed), int64(sleepPerStage*2)+int64(fault))
+	})
+}
@@ -0,0 +1,280 @@
+package hw06pipelineexecution
+
+import (
+	"sync"
+	"time"
+)
+
+// Map creates a stage applying f to every value.
+func Map(f func(v interface{}) interface{}) Stage {
+	return func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			for v := range in {
+				out <- f(v)
+			}
+		}()
+		return out
+	}
+}
+
+// Filter creates a stage passing only values matching the predicate.
+func Filter(predicate func(v interface{}) bool) Stage {
+	return func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			for v := range in {
+				if predicate(v) {
+					out <- v
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// FlatMap creates a stage emitting all values f returns for every input value.
+func FlatMap(f func(v interface{}) []interface{}) Stage {
+	return func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			for v := range in {
+				for _, res := range f(v) {
+					out <- res
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// Dedupe creates a stage dropping values with already seen keys.
+// If key is nil, the value itself is the key and must be comparable.
+func Dedupe(key func(v interface{}) interface{}) Stage {
+	if key == nil {
+		key = func(v interface{}) interface{} { return v }
+	}
+	return func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			seen := make(map[interface{}]struct{})
+			for v := range in {
+				k := key(v)
+				if _, ok := seen[k]; ok {
+					continue
+				}
+				seen[k] = struct{}{}
+				out <- v
+			}
+		}()
+		return out
+	}
+}
+
+// Batch creates a stage grouping values into []interface{} of up to size values.
+// A non-full batch is emitted when maxWait passes since its first value (if maxWait > 0)
+// or when the input is closed.
+func Batch(size int, maxWait time.Duration) Stage {
+	if size < 1 {
+		size = 1
+	}
+	return func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			batch := make([]interface{}, 0, size)
+			var timeout <-chan time.Time
+			flush := func() {
+				if len(batch) > 0 {
+					out <- batch
+					batch = make([]interface{}, 0, size)
+				}
+				timeout = nil
+			}
+			for {
+				select {
+				case v, ok := <-in:
+					if !ok {
+						flush()
+						return
+					}
+					batch = append(batch, v)
+					if len(batch) == 1 && maxWait > 0 {
+						timeout = time.After(maxWait)
+					}
+					if len(batch) == size {
+						flush()
+					}
+				case <-timeout:
+					flush()
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// MinWindowPeriod is the shortest period of TumblingWindow, shorter ones are raised to it.
+const MinWindowPeriod = time.Millisecond
+
+// TumblingWindow creates a stage grouping values received during every
+// period into []interface{}. Empty windows are not emitted.
+func TumblingWindow(period time.Duration) Stage {
+	if period < MinWindowPeriod {
+		period = MinWindowPeriod
+	}
+	return func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			ticker := time.NewTicker(period)
+			defer ticker.Stop()
+			var window []interface{}
+			for {
+				select {
+				case v, ok := <-in:
+					if !ok {
+						if len(window) > 0 {
+							out <- window
+						}
+						return
+					}
+					window = append(window, v)
+				case <-ticker.C:
+					if len(window) > 0 {
+						out <- window
+						window = nil
+					}
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// SlidingWindow creates a stage emitting the last size values as []interface{}
+// after every step values. Values left in an incomplete window are dropped.
+func SlidingWindow(size, step int) Stage {
+	if size < 1 {
+		size = 1
+	}
+	if step < 1 {
+		step = 1
+	}
+	return func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			window := make([]interface{}, 0, size)
+			received := 0
+			for v := range in {
+				if len(window) == size {
+					window = window[1:]
+				}
+				window = append(window, v)
+				received++
+				if len(window) == size && (received-size)%step == 0 {
+					out <- append([]interface{}(nil), window...)
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// RateLimit creates a stage passing no more than rate values per the period.
+// The wait for the next value is interrupted when done is closed.
+func RateLimit(done In, rate int, per time.Duration) Stage {
+	if rate < 1 {
+		rate = 1
+	}
+	interval := per / time.Duration(rate)
+	return func(in In) Out {
+		out := make(Bi)
+		go func() {
+			defer close(out)
+			var next time.Time
+			for v := range in {
+				if wait := time.Until(next); wait > 0 {
+					timer := time.NewTimer(wait)
+					select {
+					case <-done:
+						timer.Stop()
+						return
+					case <-timer.C:
+					}
+				}
+				next = time.Now().Add(interval)
+				out <- v
+			}
+		}()
+		return out
+	}
+}
+
+// Tee sends every value from in to all n outputs until in or done is closed.
+// All outputs must be read, a slow reader delays the others.
+func Tee(done In, in In, n int) []Out {
+	bis := make([]Bi, 0, n)
+	outs := make([]Out, 0, n)
+	for i := 0; i < n; i++ {
+		bi := make(Bi)
+		bis = append(bis, bi)
+		outs = append(outs, bi)
+	}
+
+	go func() {
+		defer func() {
+			for _, bi := range bis {
+				close(bi)
+			}
+			for range in { //nolint:revive
+			}
+		}()
+		for {
+			select {
+			case <-done:
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				for _, bi := range bis {
+					select {
+					case <-done:
+						return
+					case bi <- v:
+					}
+				}
+			}
+		}
+	}()
+	return outs
+}
+
+// Merge sends values from all inputs to the single output until they or done are closed.
+func Merge(done In, ins ...In) Out {
+	out := make(Bi)
+	wg := sync.WaitGroup{}
+	for _, in := range ins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range orDone(done, in) {
+				select {
+				case <-done:
+				case out <- v:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
@@ -0,0 +1,258 @@
+package hw06pipelineexecution
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// sliceSource sends values to the returned channel and closes it.
+func sliceSource(values ...interface{}) In {
+	in := make(Bi)
+	go func() {
+		defer close(in)
+		for _, v := range values {
+			in <- v
+		}
+	}()
+	return in
+}
+
+// endlessSource sends increasing numbers until done is closed.
+func endlessSource(done In) In {
+	in := make(Bi)
+	go func() {
+		defer close(in)
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case in <- i:
+			}
+		}
+	}()
+	return in
+}
+
+func readAll(out Out) []interface{} {
+	result := make([]interface{}, 0)
+	for v := range out {
+		result = append(result, v)
+	}
+	return result
+}
+
+func TestStages(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tests := []struct {
+		name     string
+		in       []interface{}
+		stages   []Stage
+		expected []interface{}
+	}{
+		{
+			name:     "map",
+			in:       []interface{}{1, 2, 3},
+			stages:   []Stage{Map(func(v interface{}) interface{} { return v.(int) * 10 })},
+			expected: []interface{}{10, 20, 30},
+		},
+		{
+			name:     "filter",
+			in:       []interface{}{1, 2, 3, 4, 5},
+			stages:   []Stage{Filter(func(v interface{}) bool { return v.(int)%2 == 1 })},
+			expected: []interface{}{1, 3, 5},
+		},
+		{
+			name: "flat map",
+			in:   []interface{}{"a b", "", "c"},
+			stages: []Stage{FlatMap(func(v interface{}) []interface{} {
+				result := make([]interface{}, 0)
+				for _, s := range strings.Fields(v.(string)) {
+					result = append(result, s)
+				}
+				return result
+			})},
+			expected: []interface{}{"a", "b", "c"},
+		},
+		{
+			name:     "dedupe",
+			in:       []interface{}{1, 2, 1, 3, 2, 4},
+			stages:   []Stage{Dedupe(nil)},
+			expected: []interface{}{1, 2, 3, 4},
+		},
+		{
+			name: "dedupe by key",
+			in:   []interface{}{"apple", "avocado", "banana", "blueberry", "cherry"},
+			stages: []Stage{Dedupe(func(v interface{}) interface{} {
+				return v.(string)[0]
+			})},
+			expected: []interface{}{"apple", "banana", "cherry"},
+		},
+		{
+			name:   "batch by size",
+			in:     []interface{}{1, 2, 3, 4, 5},
+			stages: []Stage{Batch(2, 0)},
+			expected: []interface{}{
+				[]interface{}{1, 2},
+				[]interface{}{3, 4},
+				[]interface{}{5},
+			},
+		},
+		{
+			name:   "sliding window",
+			in:     []interface{}{1, 2, 3, 4, 5, 6},
+			stages: []Stage{SlidingWindow(3, 2)},
+			expected: []interface{}{
+				[]interface{}{1, 2, 3},
+				[]interface{}{3, 4, 5},
+			},
+		},
+		{
+			name:   "sliding window by one",
+			in:     []interface{}{1, 2, 3, 4},
+			stages: []Stage{SlidingWindow(2, 1)},
+			expected: []interface{}{
+				[]interface{}{1, 2},
+				[]interface{}{2, 3},
+				[]interface{}{3, 4},
+			},
+		},
+		{
+			name:     "combined",
+			in:       []interface{}{1, 2, 2, 3, 4, 4, 5},
+			stages:   []Stage{Dedupe(nil), Filter(func(v interface{}) bool { return v.(int) > 1 }), Batch(3, 0)},
+			expected: []interface{}{[]interface{}{2, 3, 4}, []interface{}{5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := readAll(ExecutePipeline(sliceSource(tt.in...), nil, tt.stages...))
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestTimedStages(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("batch by time", func(t *testing.T) {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			in <- 1
+			in <- 2
+			time.Sleep(sleepPerStage)
+			in <- 3
+		}()
+
+		result := readAll(ExecutePipeline(in, nil, Batch(10, sleepPerStage/2)))
+		require.Equal(t, []interface{}{[]interface{}{1, 2}, []interface{}{3}}, result)
+	})
+
+	t.Run("tumbling window", func(t *testing.T) {
+		in := make(Bi)
+		go func() {
+			defer close(in)
+			in <- 1
+			in <- 2
+			time.Sleep(sleepPerStage * 3 / 2)
+			in <- 3
+		}()
+
+		result := readAll(ExecutePipeline(in, nil, TumblingWindow(sleepPerStage)))
+		require.Equal(t, []interface{}{[]interface{}{1, 2}, []interface{}{3}}, result)
+	})
+
+	t.Run("tumbling window with non-positive period", func(t *testing.T) {
+		for _, period := range []time.Duration{0, -time.Second} {
+			result := make([]interface{}, 0)
+			for window := range ExecutePipeline(sliceSource(1, 2, 3), nil, TumblingWindow(period)) {
+				result = append(result, window.([]interface{})...)
+			}
+			require.Equal(t, []interface{}{1, 2, 3}, result)
+		}
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		values := []interface{}{1, 2, 3, 4, 5}
+		start := time.Now()
+		result := readAll(ExecutePipeline(sliceSource(values...), nil, RateLimit(nil, 10, sleepPerStage*2)))
+		elapsed := time.Since(start)
+
+		require.Equal(t, values, result)
+		// 10 values per 200ms is one value per 20ms.
+		require.GreaterOrEqual(t, int64(elapsed), int64(sleepPerStage*2/10)*int64(len(values)-1))
+		require.Less(t, int64(elapsed), int64(sleepPerStage))
+	})
+
+	t.Run("done interrupts rate limit", func(t *testing.T) {
+		done := make(Bi)
+		go func() {
+			<-time.After(sleepPerStage)
+			close(done)
+		}()
+
+		start := time.Now()
+		result := readAll(ExecutePipeline(endlessSource(done), done, RateLimit(done, 1, time.Minute)))
+		require.Equal(t, []interface{}{0}, result)
+		require.Less(t, int64(time.Since(start)), int64(sleepPerStage)+int64(fault))
+	})
+}
+
+func TestTeeAndMerge(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("tee and merge", func(t *testing.T) {
+		outs := Tee(nil, sliceSource(1, 2, 3), 3)
+		require.Len(t, outs, 3)
+
+		result := make([]int, 0)
+		for v := range Merge(nil, outs...) {
+			result = append(result, v.(int))
+		}
+		sort.Ints(result)
+		require.Equal(t, []int{1, 1, 1, 2, 2, 2, 3, 3, 3}, result)
+	})
+
+	t.Run("done stops tee and merge", func(t *testing.T) {
+		done := make(Bi)
+		outs := Tee(done, endlessSource(done), 2)
+
+		count := 0
+		for range Merge(done, outs...) {
+			count++
+			if count == 10 {
+				close(done)
+			}
+		}
+		require.GreaterOrEqual(t, count, 10)
+	})
+
+	t.Run("done stops stages", func(t *testing.T) {
+		done := make(Bi)
+		go func() {
+			<-time.After(sleepPerStage)
+			close(done)
+		}()
+
+		start := time.Now()
+		out := ExecutePipeline(endlessSource(done), done,
+			Map(func(v interface{}) interface{} { return v.(int) + 1 }),
+			Filter(func(v interface{}) bool { return v.(int)%2 == 0 }),
+			FlatMap(func(v interface{}) []interface{} { return []interface{}{v, v} }),
+			Dedupe(nil),
+			RateLimit(done, 1000, time.Second),
+			Batch(5, sleepPerStage/10),
+			SlidingWindow(2, 1),
+			TumblingWindow(sleepPerStage/10),
+		)
+		readAll(out)
+		require.Less(t, int64(time.Since(start)), int64(sleepPerStage)+int64(fault))
+	})
+}
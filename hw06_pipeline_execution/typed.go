@@ -0,0 +1,32 @@
+package hw06pipelineexecution
+
+// TypedStage is a Stage with typed input and output values.
+type TypedStage[I, O any] func(in <-chan I) (out <-chan O)
+
+// Pipeline is a chain of typed stages turning values of type I into values of type O.
+type Pipeline[I, O any] struct {
+	run func(done In, in <-chan I) <-chan O
+}
+
+// NewPipeline creates a pipeline of the single stage.
+func NewPipeline[I, O any](stage TypedStage[I, O]) Pipeline[I, O] {
+	return Pipeline[I, O]{
+		run: func(done In, in <-chan I) <-chan O {
+			return stage(orDone(done, in))
+		},
+	}
+}
+
+// Then appends the stage to the pipeline. The stage input type must match the pipeline output type.
+func Then[I, M, O any](p Pipeline[I, M], stage TypedStage[M, O]) Pipeline[I, O] {
+	return Pipeline[I, O]{
+		run: func(done In, in <-chan I) <-chan O {
+			return stage(orDone(done, p.run(done, in)))
+		},
+	}
+}
+
+// Execute runs the pipeline. Closing done stops all stages like in ExecutePipeline.
+func (p Pipeline[I, O]) Execute(in <-chan I, done In) <-chan O {
+	return orDone(done, p.run(done, in))
+}
@@ -0,0 +1,127 @@
+package hw06pipelineexecution
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedPipeline(t *testing.T) {
+	wg := sync.WaitGroup{}
+	// Typed stage generator
+	g := func(f func(v int) int) TypedStage[int, int] {
+		return func(in <-chan int) <-chan int {
+			out := make(chan int)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer close(out)
+				for v := range in {
+					time.Sleep(sleepPerStage)
+					out <- f(v)
+				}
+			}()
+			return out
+		}
+	}
+	stringifier := func(in <-chan int) <-chan string {
+		out := make(chan string)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(out)
+			for v := range in {
+				time.Sleep(sleepPerStage)
+				out <- strconv.Itoa(v)
+			}
+		}()
+		return out
+	}
+
+	pipeline := Then(
+		Then(
+			Then(
+				NewPipeline(g(func(v int) int { return v })),
+				g(func(v int) int { return v * 2 }),
+			),
+			g(func(v int) int { return v + 100 }),
+		),
+		stringifier,
+	)
+	stagesCount := 4
+
+	t.Run("simple case", func(t *testing.T) {
+		in := make(chan int)
+		data := []int{1, 2, 3, 4, 5}
+
+		go func() {
+			for _, v := range data {
+				in <- v
+			}
+			close(in)
+		}()
+
+		result := make([]string, 0, 10)
+		start := time.Now()
+		for s := range pipeline.Execute(in, nil) {
+			result = append(result, s)
+		}
+		elapsed := time.Since(start)
+		wg.Wait()
+
+		require.Equal(t, []string{"102", "104", "106", "108", "110"}, result)
+		require.Less(t,
+			int64(elapsed),
+			int64(sleepPerStage)*int64(stagesCount+len(data)-1)+int64(fault))
+	})
+
+	t.Run("done case", func(t *testing.T) {
+		in := make(chan int)
+		done := make(Bi)
+		data := []int{1, 2, 3, 4, 5}
+
+		// Abort after 200ms
+		abortDur := sleepPerStage * 2
+		go func() {
+			<-time.After(abortDur)
+			close(done)
+		}()
+
+		go func() {
+			for _, v := range data {
+				in <- v
+			}
+			close(in)
+		}()
+
+		result := make([]string, 0, 10)
+		start := time.Now()
+		for s := range pipeline.Execute(in, done) {
+			result = append(result, s)
+		}
+		elapsed := time.Since(start)
+		wg.Wait()
+
+		require.Len(t, result, 0)
+		require.Less(t, int64(elapsed), int64(abortDur)+int64(fault))
+	})
+
+	t.Run("pipeline is reusable", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			in := make(chan int, 1)
+			in <- i
+			close(in)
+
+			result := make([]string, 0, 1)
+			for s := range pipeline.Execute(in, nil) {
+				result = append(result, s)
+			}
+			wg.Wait()
+
+			require.Equal(t, []string{strconv.Itoa(i*2 + 100)}, result)
+		}
+	})
+}
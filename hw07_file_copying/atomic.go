@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const (
+	PartialSuffix   = ".part"
+	DefaultFileMode = 0o644
+)
+
+// Create temporary file in the destination directory.
+func createTemp(toPath string) (*os.File, error) {
+	return os.CreateTemp(filepath.Dir(toPath), "."+filepath.Base(toPath)+".*.tmp")
+}
+
+// Flush the written file to disk and atomically move it to the destination.
+// The mode of the replaced destination is kept.
+func commitFile(file *os.File, toPath string) error {
+	mode := os.FileMode(DefaultFileMode)
+	if info, err := os.Stat(toPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := file.Chmod(mode); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	if err := os.Rename(file.Name(), toPath); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(toPath))
+}
+
+// Flush directory entries to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err
+	}
+	return nil
+}
+
+// Move the temporary file of a failed copy next to the destination to resume it later.
+func keepTemp(file *os.File, toPath string) error {
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	return os.Rename(file.Name(), toPath+PartialSuffix)
+}
+
+// Remove the temporary file of a failed copy.
+func discardTemp(file *os.File) error {
+	file.Close()
+	if err := os.Remove(file.Name()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
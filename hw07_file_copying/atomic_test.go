@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAtomicCopy(t *testing.T) {
+	t.Run("existing destination is replaced", func(t *testing.T) {
+		dir := t.TempDir()
+		toPath := filepath.Join(dir, "out.txt")
+		require.NoError(t, os.WriteFile(toPath, []byte("old content"), 0o600))
+
+		err := Copy("testdata/input.txt", toPath, 0, 10)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(toPath)
+		require.NoError(t, err)
+		correct, err := os.ReadFile("testdata/out_offset0_limit10.txt")
+		require.NoError(t, err)
+		require.Equal(t, correct, data)
+
+		info, err := os.Stat(toPath)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "destination mode was not kept")
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "temporary file was left")
+	})
+
+	t.Run("new destination mode", func(t *testing.T) {
+		toPath := filepath.Join(t.TempDir(), "out.txt")
+		require.NoError(t, Copy("testdata/input.txt", toPath, 0, 0))
+
+		info, err := os.Stat(toPath)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(DefaultFileMode), info.Mode().Perm())
+	})
+
+	t.Run("failed copy keeps destination untouched", func(t *testing.T) {
+		dir := t.TempDir()
+		toPath := filepath.Join(dir, "out.txt")
+		require.NoError(t, os.WriteFile(toPath, []byte("old content"), 0o644))
+
+		err := Copy("testdata/input.txt", toPath, 10000, 0)
+		require.ErrorIs(t, err, ErrOffsetExceedsFileSize)
+
+		data, err := os.ReadFile(toPath)
+		require.NoError(t, err)
+		require.Equal(t, "old content", string(data))
+	})
+
+	t.Run("failed commit removes temporary file", func(t *testing.T) {
+		dir := t.TempDir()
+		toPath := filepath.Join(dir, "out")
+		require.NoError(t, os.Mkdir(toPath, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(toPath, "keep.txt"), []byte("keep"), 0o644))
+
+		err := Copy("testdata/input.txt", toPath, 0, 0)
+		require.Error(t, err)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "temporary file was left")
+		data, err := os.ReadFile(filepath.Join(toPath, "keep.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "keep", string(data))
+	})
+
+	t.Run("discard temp", func(t *testing.T) {
+		toPath := filepath.Join(t.TempDir(), "out.txt")
+		file, err := createTemp(toPath)
+		require.NoError(t, err)
+		require.Equal(t, filepath.Dir(toPath), filepath.Dir(file.Name()))
+
+		discardTemp(file)
+		_, err = os.Stat(file.Name())
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	ChecksumSHA256 = "sha256"
+	ChecksumMD5    = "md5"
+	ChecksumCRC32C = "crc32c"
+)
+
+var (
+	ErrUnsupportedChecksum = errors.New("unsupported checksum algorithm")
+	ErrChecksumMismatch    = errors.New("checksum mismatch")
+)
+
+// Create hash for the checksum algorithm.
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	case ChecksumMD5:
+		return md5.New(), nil //nolint:gosec
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedChecksum, algorithm)
+	}
+}
+
+// Check the first size bytes of the written file have the expected digest.
+func verifyFile(file *os.File, size int64, algorithm string, expected []byte) error {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(h, io.NewSectionReader(file, 0, size)); err != nil {
+		return err
+	}
+	if actual := h.Sum(nil); !bytes.Equal(actual, expected) {
+		return fmt.Errorf("%w: expected %x, got %x", ErrChecksumMismatch, expected, actual)
+	}
+	return nil
+}
+
+// Write digest to the sidecar file in the format of sha256sum and similar tools.
+func writeChecksumFile(path, digest, copiedPath string) error {
+	line := digest + "  " + filepath.Base(copiedPath) + "\n"
+	return os.WriteFile(path, []byte(line), DefaultFileMode)
+}
+
+// Hash the copied bytes, verify them in the written file and report the digest.
+type checksummer struct {
+	algorithm string
+	verify    bool
+	report    func(digest string)
+	hash      hash.Hash
+}
+
+// Create checksummer for the options or nil if no checksum is requested.
+func newChecksummer(o *options) (*checksummer, error) {
+	if o.checksum == "" {
+		if o.verify {
+			return nil, fmt.Errorf("%w: verify requires checksum", ErrConflictingArgs)
+		}
+		return nil, nil
+	}
+	h, err := newHash(o.checksum)
+	if err != nil {
+		return nil, err
+	}
+	return &checksummer{algorithm: o.checksum, verify: o.verify, report: o.report, hash: h}, nil
+}
+
+// Wrap the reader to hash everything read through it.
+func (c *checksummer) tee(r io.Reader) io.Reader {
+	if c == nil {
+		return r
+	}
+	return io.TeeReader(r, c.hash)
+}
+
+// Wrap the writer to hash everything written through it.
+func (c *checksummer) teeWriter(w io.Writer) io.Writer {
+	if c == nil {
+		return w
+	}
+	return io.MultiWriter(w, c.hash)
+}
+
+// Verify the written file if requested.
+func (c *checksummer) check(file *os.File, size int64) error {
+	if c == nil || !c.verify {
+		return nil
+	}
+	return verifyFile(file, size, c.algorithm, c.hash.Sum(nil))
+}
+
+// Report the digest in hex.
+func (c *checksummer) done() {
+	if c == nil || c.report == nil {
+		return
+	}
+	c.report(hex.EncodeToString(c.hash.Sum(nil)))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyChecksum(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		offset    int64
+		limit     int64
+		resume    bool
+		expected  string
+	}{
+		{
+			name:      "sha256",
+			algorithm: ChecksumSHA256,
+			expected:  "55aedc99815a6ba613c29881bad22313ad1b067d3b4e24a65a19a2638d874e57",
+		},
+		{
+			name:      "sha256 with offset and limit",
+			algorithm: ChecksumSHA256,
+			offset:    100,
+			limit:     1000,
+			expected:  "ed523fc33a33551f7578b51df8ad90d85fb82820456330fb7cda166737dc205d",
+		},
+		{
+			name:      "md5",
+			algorithm: ChecksumMD5,
+			offset:    100,
+			limit:     1000,
+			expected:  "0941353895037c82277d3cf349dc4b7f",
+		},
+		{
+			name:      "crc32c",
+			algorithm: ChecksumCRC32C,
+			limit:     10,
+			expected:  "e16bf0a6",
+		},
+		{
+			name:      "resume",
+			algorithm: ChecksumCRC32C,
+			offset:    100,
+			limit:     1000,
+			resume:    true,
+			expected:  "d7d8d494",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toPath := filepath.Join(t.TempDir(), "out.txt")
+
+			var digest string
+			opts := []Option{WithChecksum(tt.algorithm, func(d string) { digest = d }), WithVerify()}
+			if tt.resume {
+				correct, err := os.ReadFile("testdata/out_offset100_limit1000.txt")
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(toPath+PartialSuffix, correct[:300], 0o644))
+				opts = append(opts, WithResume())
+			}
+
+			err := Copy("testdata/input.txt", toPath, tt.offset, tt.limit, opts...)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, digest)
+		})
+	}
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		toPath := filepath.Join(t.TempDir(), "out.txt")
+		err := Copy("testdata/input.txt", toPath, 0, 0, WithChecksum("sha1", nil))
+		require.ErrorIs(t, err, ErrUnsupportedChecksum)
+
+		_, err = os.Stat(toPath)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("verify without checksum", func(t *testing.T) {
+		toPath := filepath.Join(t.TempDir(), "out.txt")
+		err := Copy("testdata/input.txt", toPath, 0, 0, WithVerify())
+		require.ErrorIs(t, err, ErrConflictingArgs)
+		require.Equal(t, ExitBadArgs, exitCode(err))
+
+		_, err = os.Stat(toPath)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestVerifyFile(t *testing.T) {
+	file, err := os.Open("testdata/out_offset0_limit10.txt")
+	require.NoError(t, err)
+	defer file.Close()
+
+	h, err := newHash(ChecksumSHA256)
+	require.NoError(t, err)
+	_, err = h.Write([]byte("Go\nDocumen"))
+	require.NoError(t, err)
+	expected := h.Sum(nil)
+
+	content, err := os.ReadFile("testdata/out_offset0_limit10.txt")
+	require.NoError(t, err)
+	require.Equal(t, "Go\nDocumen", string(content))
+
+	require.NoError(t, verifyFile(file, 10, ChecksumSHA256, expected))
+	require.ErrorIs(t, verifyFile(file, 9, ChecksumSHA256, expected), ErrChecksumMismatch)
+}
+
+func TestWriteChecksumFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt.sha256")
+	require.NoError(t, writeChecksumFile(path, "abc123", "/some/dir/out.txt"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "abc123  out.txt\n", string(data))
+}
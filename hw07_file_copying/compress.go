@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+var (
+	ErrUnsupportedCompression = errors.New("unsupported compression")
+	ErrUnknownFormat          = errors.New("unknown compressed format")
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// WithCompress makes Copy compress the copied bytes with gzip or zstd.
+func WithCompress(format string) Option {
+	return func(o *options) {
+		o.compress = format
+	}
+}
+
+// WithDecompress makes Copy decompress the copied bytes, the format is detected by magic bytes.
+func WithDecompress() Option {
+	return func(o *options) {
+		o.decompress = true
+	}
+}
+
+// Check the bytes are transformed on the way to the destination.
+func (o *options) transforms() bool {
+	return o.compress != "" || o.decompress
+}
+
+// Check compression options. Resume compares the destination with the source, so it can't be used.
+func checkCompression(o *options) error {
+	switch {
+	case o.compress != "" && o.compress != CompressGzip && o.compress != CompressZstd:
+		return fmt.Errorf("%w: %q", ErrUnsupportedCompression, o.compress)
+	case o.compress != "" && o.decompress:
+		return fmt.Errorf("%w: compress and decompress can't be used together", ErrUnsupportedCompression)
+	case o.transforms() && o.resume:
+		return fmt.Errorf("%w: resume is not supported", ErrUnsupportedCompression)
+	default:
+		return nil
+	}
+}
+
+// Create writer compressing to w.
+func newCompressor(format string, w io.Writer) (io.WriteCloser, error) {
+	switch format {
+	case CompressGzip:
+		return gzip.NewWriter(w), nil
+	case CompressZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedCompression, format)
+	}
+}
+
+// Detect format by magic bytes and create reader decompressing r.
+func newDecompressor(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		d, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// Copy limit bytes of the source through compression or decompression.
+// The digest is of the written bytes, so the written count is returned.
+func copyTransformed(dst io.Writer, src io.Reader, limit int64, o *options, sum *checksummer) (int64, error) {
+	if limit > 0 {
+		src = io.LimitReader(src, limit)
+	}
+	cw := &countingWriter{w: sum.teeWriter(dst)}
+
+	if o.decompress {
+		r, err := newDecompressor(src)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+		_, err = io.Copy(cw, r)
+		return cw.n, err
+	}
+
+	w, err := newCompressor(o.compress, cw)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		w.Close()
+		return cw.n, err
+	}
+	err = w.Close()
+	return cw.n, err
+}
+
+// Writer counting written bytes.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyCompress(t *testing.T) {
+	input, err := os.ReadFile("testdata/input.txt")
+	require.NoError(t, err)
+	expected := input[100:1100]
+
+	tests := []struct {
+		format     string
+		decompress func(r io.Reader) (io.Reader, error)
+		magic      []byte
+	}{
+		{
+			format: CompressGzip,
+			decompress: func(r io.Reader) (io.Reader, error) {
+				return gzip.NewReader(r)
+			},
+			magic: gzipMagic,
+		},
+		{
+			format: CompressZstd,
+			decompress: func(r io.Reader) (io.Reader, error) {
+				return zstd.NewReader(r)
+			},
+			magic: zstdMagic,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			dir := t.TempDir()
+			compressedPath := filepath.Join(dir, "out."+tt.format)
+			var progress bytes.Buffer
+			p, err := NewProgress(ProgressJSON, &progress)
+			require.NoError(t, err)
+
+			err = Copy("testdata/input.txt", compressedPath, 100, 1000, WithCompress(tt.format), WithProgress(p))
+			require.NoError(t, err)
+
+			compressed, err := os.ReadFile(compressedPath)
+			require.NoError(t, err)
+			require.True(t, bytes.HasPrefix(compressed, tt.magic))
+			r, err := tt.decompress(bytes.NewReader(compressed))
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, expected, data)
+
+			// Progress counts source bytes
+			lines := strings.Split(strings.TrimSpace(progress.String()), "\n")
+			var last ProgressEvent
+			require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+			require.Equal(t, int64(1000), last.Bytes)
+
+			decompressedPath := filepath.Join(dir, "out.txt")
+			err = Copy(compressedPath, decompressedPath, 0, 0, WithDecompress())
+			require.NoError(t, err)
+			data, err = os.ReadFile(decompressedPath)
+			require.NoError(t, err)
+			require.Equal(t, expected, data)
+		})
+	}
+}
+
+func TestCopyCompressChecksum(t *testing.T) {
+	toPath := filepath.Join(t.TempDir(), "out.gz")
+	var digest string
+
+	err := Copy("testdata/input.txt", toPath, 0, 0, WithCompress(CompressGzip),
+		WithChecksum(ChecksumSHA256, func(d string) { digest = d }), WithVerify())
+	require.NoError(t, err)
+
+	// The digest is of the compressed destination
+	data, err := os.ReadFile(toPath)
+	require.NoError(t, err)
+	expected := sha256.Sum256(data)
+	require.Equal(t, hex.EncodeToString(expected[:]), digest)
+}
+
+func TestCopyCompressErrors(t *testing.T) {
+	toPath := filepath.Join(t.TempDir(), "out")
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		expectedErr error
+	}{
+		{name: "unknown format", opts: []Option{WithDecompress()}, expectedErr: ErrUnknownFormat},
+		{name: "unsupported compression", opts: []Option{WithCompress("bzip2")}, expectedErr: ErrUnsupportedCompression},
+		{
+			name:        "compress and decompress",
+			opts:        []Option{WithCompress(CompressGzip), WithDecompress()},
+			expectedErr: ErrUnsupportedCompression,
+		},
+		{
+			name:        "resume",
+			opts:        []Option{WithCompress(CompressZstd), WithResume()},
+			expectedErr: ErrUnsupportedCompression,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Copy("testdata/input.txt", toPath, 0, 0, tt.opts...)
+			require.ErrorIs(t, err, tt.expectedErr)
+
+			_, err = os.Stat(toPath)
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
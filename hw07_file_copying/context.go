@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// WithKeepPartial makes a failed or cancelled copy keep the written part next to
+// the destination with PartialSuffix, so it can be continued with WithResume.
+func WithKeepPartial() Option {
+	return func(o *options) {
+		o.keepPartial = true
+	}
+}
+
+// Keep the temporary file of a failed copy as partial file if requested, otherwise remove it.
+func abandonTemp(o *options, file *os.File, toPath string) {
+	if o.keepPartial {
+		if err := keepTemp(file, toPath); err != nil {
+			o.logger.Error("Error keeping partial file", "error", err, "path", file.Name())
+			return
+		}
+		o.logger.Info("Partial file is kept", "path", toPath+PartialSuffix)
+		return
+	}
+	if err := discardTemp(file); err != nil {
+		o.logger.Error("Error removing temporary file", "error", err, "path", file.Name())
+	}
+}
+
+// Reader checking the context before each read.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// ReaderAt checking the context before each read.
+type contextReaderAt struct {
+	ctx context.Context
+	r   io.ReaderAt
+}
+
+func (c *contextReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.ReadAt(p, off)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Clock cancelling the copy when the rate limit sleeps.
+type cancelClock struct {
+	fakeClock
+	cancel context.CancelFunc
+}
+
+func (c *cancelClock) Sleep(time.Duration) {
+	c.cancel()
+}
+
+// Check the directory has no files but the expected ones.
+func requireFiles(t *testing.T, dir string, expected ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	require.ElementsMatch(t, expected, names)
+}
+
+func TestCopyContext(t *testing.T) {
+	input, err := os.ReadFile("testdata/input.txt")
+	require.NoError(t, err)
+
+	t.Run("cancelled", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := CopyContext(ctx, "testdata/input.txt", filepath.Join(dir, "out.txt"), 0, 0)
+		require.ErrorIs(t, err, context.Canceled)
+		requireFiles(t, dir)
+	})
+
+	t.Run("cancelled parallel", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := CopyContext(ctx, "testdata/input.txt", filepath.Join(dir, "out.txt"), 0, 0, WithWorkers(4))
+		require.ErrorIs(t, err, context.Canceled)
+		requireFiles(t, dir)
+	})
+
+	t.Run("cancelled between reads", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		clock := &cancelClock{cancel: cancel}
+
+		// The first read is in the full bucket, the second one sleeps and cancels
+		err := CopyContext(ctx, "testdata/input.txt", filepath.Join(dir, "out.txt"), 0, 1000,
+			WithRate(100), WithClock(clock))
+		require.ErrorIs(t, err, context.Canceled)
+		requireFiles(t, dir)
+	})
+
+	t.Run("keep partial and resume", func(t *testing.T) {
+		dir := t.TempDir()
+		toPath := filepath.Join(dir, "out.txt")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		clock := &cancelClock{cancel: cancel}
+
+		err := CopyContext(ctx, "testdata/input.txt", toPath, 0, 1000,
+			WithRate(100), WithClock(clock), WithKeepPartial())
+		require.ErrorIs(t, err, context.Canceled)
+		requireFiles(t, dir, "out.txt"+PartialSuffix)
+
+		partial, err := os.ReadFile(toPath + PartialSuffix)
+		require.NoError(t, err)
+		require.Equal(t, input[:200], partial)
+
+		err = Copy("testdata/input.txt", toPath, 0, 1000, WithResume())
+		require.NoError(t, err)
+		data, err := os.ReadFile(toPath)
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(input[:1000], data), "file contents are not equal")
+	})
+
+	t.Run("cancelled tree", func(t *testing.T) {
+		from := makeTree(t)
+		to := filepath.Join(t.TempDir(), "dst")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := CopyTreeContext(ctx, from, to)
+		require.ErrorIs(t, err, context.Canceled)
+		requireFiles(t, filepath.Join(to, "a"))
+	})
+}
+
+func TestExitCodeCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := CopyContext(ctx, "testdata/input.txt", filepath.Join(t.TempDir(), "out.txt"), 0, 0)
+	require.Equal(t, ExitCancelled, exitCode(err))
+}
@@ -1,23 +1,27 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
-
-	pb "github.com/cheggaaa/pb/v3"
-)
-
-const (
-	BarWidth = 100
 )
 
 var (
 	ErrUnsupportedFile       = errors.New("unsupported file")
 	ErrOffsetExceedsFileSize = errors.New("offset exceeds file size")
+	ErrEmptySource           = errors.New("source file path is empty")
+	ErrEmptyDestination      = errors.New("destination file path is empty")
+	ErrSamePaths             = errors.New("source and destination file paths are same. Must be different")
+	ErrNegativeLimit         = errors.New("limit cannot be negative")
 )
 
+// Logger dropping all records, it is used by Copy without WithLogger.
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
 // ComparePaths compares two paths.
 func ComparePaths(path1, path2 string) (bool, error) {
 	absPath1, err := filepath.Abs(path1)
@@ -31,35 +35,35 @@ func ComparePaths(path1, path2 string) (bool, error) {
 	return absPath1 == absPath2, nil
 }
 
-// Check arguments.
+// Check arguments. Negative offset is counted from the end of file and is checked with its size.
 func CheckArgs(from, to string, offset, limit int64) error {
 	if from == "" {
-		return errors.New("source file path is empty")
+		return ErrEmptySource
 	}
 	if to == "" {
-		return errors.New("destination file path is empty")
+		return ErrEmptyDestination
 	}
 
-	samePath, err := ComparePaths(from, to)
-	if err != nil {
-		return err
-	}
-	if samePath {
-		return errors.New("source and destination file paths are same. Must be different")
+	if from != StdStream && to != StdStream {
+		samePath, err := ComparePaths(from, to)
+		if err != nil {
+			return err
+		}
+		if samePath {
+			return ErrSamePaths
+		}
 	}
 
-	if offset < 0 {
-		return errors.New("offset cannot be negative")
-	}
 	if limit < 0 {
-		return errors.New("limit cannot be negative")
+		return ErrNegativeLimit
 	}
 	return nil
 }
 
 // Check is available file and not a directory.
-func CheckFile(fromPath string) (os.FileInfo, error) {
-	fileInfo, err := os.Stat(fromPath)
+// Pipes are read until EOF, devices have no end and are supported only with limit.
+func CheckFile(fromPath string, limit int64) (os.FileInfo, error) {
+	fileInfo, err := statSource(fromPath)
 	if err != nil {
 		return nil, err
 	}
@@ -68,11 +72,15 @@ func CheckFile(fromPath string) (os.FileInfo, error) {
 		return nil, ErrUnsupportedFile
 	}
 
-	if fileInfo.Mode()&os.ModeType != 0 {
+	mode := fileInfo.Mode()
+	switch {
+	case mode.IsRegular(), mode&(os.ModeNamedPipe|os.ModeSocket) != 0:
+		return fileInfo, nil
+	case mode&os.ModeDevice != 0 && limit > 0:
+		return fileInfo, nil
+	default:
 		return nil, ErrUnsupportedFile
 	}
-
-	return fileInfo, nil
 }
 
 // Check offset.
@@ -92,90 +100,328 @@ func GetFileSize(path string) (int64, error) {
 	return fileInfo.Size(), nil
 }
 
+// Option configures Copy.
+type Option func(*options)
+
+type options struct {
+	resume      bool
+	checksum    string
+	verify      bool
+	report      func(digest string)
+	symlinks    SymlinkPolicy
+	existing    ExistingPolicy
+	workers     int
+	rate        int64
+	clock       Clock
+	progress    Progress
+	compress    string
+	decompress  bool
+	logger      *slog.Logger
+	keepPartial bool
+}
+
+// Apply options over the defaults.
+func applyOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = discardLogger
+	}
+	if o.progress == nil {
+		o.progress = noProgress{}
+	}
+	return o
+}
+
+// WithLogger sets the logger of the copy steps, nothing is logged by default.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithResume makes Copy continue a partial destination left by an interrupted copy.
+func WithResume() Option {
+	return func(o *options) {
+		o.resume = true
+	}
+}
+
+// WithChecksum makes Copy compute the digest of the copied bytes while streaming
+// and pass it in hex to report. Supported algorithms are sha256, md5 and crc32c.
+func WithChecksum(algorithm string, report func(digest string)) Option {
+	return func(o *options) {
+		o.checksum = algorithm
+		o.report = report
+	}
+}
+
+// WithVerify makes Copy re-read the written destination and compare its digest
+// before replacing the destination. It requires WithChecksum.
+func WithVerify() Option {
+	return func(o *options) {
+		o.verify = true
+	}
+}
+
 // Copy file.
-func Copy(fromPath, toPath string, offset, limit int64) error {
+func Copy(fromPath, toPath string, offset, limit int64, opts ...Option) error {
+	return CopyContext(context.Background(), fromPath, toPath, offset, limit, opts...)
+}
+
+// CopyContext copies file until the context is done, it is checked between reads.
+// The written part is removed on failure unless WithKeepPartial is set.
+func CopyContext(ctx context.Context, fromPath, toPath string, offset, limit int64, opts ...Option) error {
+	o := applyOptions(opts)
+
 	if err := CheckArgs(fromPath, toPath, offset, limit); err != nil {
-		logger.Error("Error validating arguments", "error", err)
+		o.logger.Error("Error validating arguments", "error", err)
 		return err
 	}
-	logger.Info("Arguments validated", "from", fromPath, "to", toPath, "offset", offset, "limit", limit)
-
-	fileInfo, err := CheckFile(fromPath)
+	sum, err := newChecksummer(&o)
 	if err != nil {
-		logger.Error("Error checking source file", "error", err)
+		o.logger.Error("Error validating arguments", "error", err)
 		return err
 	}
-	logger.Info("File exists and is valid", "path", fromPath)
+	if err := checkCompression(&o); err != nil {
+		o.logger.Error("Error validating arguments", "error", err)
+		return err
+	}
+	o.logger.Info("Arguments validated", "from", fromPath, "to", toPath, "offset", offset, "limit", limit)
 
-	// Open source file
-	fromFile, err := os.Open(fromPath)
-	if err != nil {
-		logger.Error("Error opening source file", "error", err)
+	if toPath, err = resolveDestination(toPath); err != nil {
+		o.logger.Error("Error resolving destination link", "error", err)
 		return err
 	}
-	defer fromFile.Close()
-	logger.Info("Opened source file", "path", fromPath)
 
-	// Create destination file
-	toFile, err := os.Create(toPath)
+	fileInfo, err := CheckFile(fromPath, limit)
 	if err != nil {
-		logger.Error("Error creating destination file", "error", err)
+		o.logger.Error("Error checking source file", "error", err)
 		return err
 	}
-	defer toFile.Close()
-	logger.Info("Created destination file", "path", toPath)
+	stream := isStream(fileInfo)
+	if err := checkStreams(&o, stream, toPath); err != nil {
+		o.logger.Error("Error validating arguments", "error", err)
+		return err
+	}
+	if err := checkWorkers(&o, stream, toPath); err != nil {
+		o.logger.Error("Error validating arguments", "error", err)
+		return err
+	}
+	o.logger.Info("File exists and is valid", "path", fromPath)
 
-	// Set offset if needed
-	if offset > 0 {
-		if err := CheckOffset(fileInfo, offset); err != nil {
-			logger.Error("Error checking offset", "error", err)
+	// Resolve offset from the end of file
+	if offset < 0 {
+		if stream {
+			err = fmt.Errorf("%w: tail offset needs file size", ErrUnsupportedStream)
+		} else {
+			offset, err = resolveOffset(fileInfo.Size(), offset)
+		}
+		if err != nil {
+			o.logger.Error("Error checking offset", "error", err)
 			return err
 		}
-		if _, err := fromFile.Seek(offset, io.SeekStart); err != nil {
-			logger.Error("Error setting file offset", "error", err)
+		o.logger.Info("Resolved tail offset", "offset", offset)
+	}
+
+	// Open source file
+	fromFile, err := openSource(fromPath)
+	if err != nil {
+		o.logger.Error("Error opening source file", "error", err)
+		return err
+	}
+	defer closeSource(fromFile)
+	o.logger.Info("Opened source file", "path", fromPath)
+	source := &contextReader{ctx: ctx, r: fromFile}
+
+	// Check offset
+	if offset > 0 && !stream {
+		if err := CheckOffset(fileInfo, offset); err != nil {
+			o.logger.Error("Error checking offset", "error", err)
 			return err
 		}
-		logger.Info("Set file offset", "offset", offset)
 	}
 
-	// Get file size and unit
+	// Get file size and unit, the size of a stream is unknown so limit is used
 	var sizeToCopy int64
 	fileSize := fileInfo.Size()
 	fileSizeWithOffset := fileSize - offset
-	if limit == 0 || limit > fileSizeWithOffset {
+	switch {
+	case stream:
+		sizeToCopy = limit
+	case limit == 0 || limit > fileSizeWithOffset:
 		sizeToCopy = fileSizeWithOffset
-	} else {
+	default:
 		sizeToCopy = limit
 	}
 
-	// Create progress and start bar
-	tmpl := `{{ bar . "[" "=" ">" " " "]"}} {{counters .}}`
-	bar := pb.ProgressBarTemplate(tmpl).Start64(sizeToCopy)
-	bar.SetMaxWidth(BarWidth)
-	barReader := bar.NewProxyReader(fromFile)
+	if o.resume {
+		return copyResumable(ctx, fromFile, fromPath, toPath, offset, limit, sizeToCopy, &o, sum)
+	}
 
-	// Copy file
-	if limit == 0 {
-		_, err = io.Copy(toFile, barReader)
-	} else {
-		_, err = io.CopyN(toFile, barReader, limit)
+	src := &copySource{
+		file: fromFile, reader: source, path: fromPath, stream: stream,
+		offset: offset, limit: limit, size: sizeToCopy,
 	}
+	return copyToDestination(ctx, &o, sum, src, toPath)
+}
 
+// Range of the opened source to copy, the size of a stream is its limit.
+type copySource struct {
+	file   *os.File
+	reader io.Reader
+	path   string
+	stream bool
+	offset int64
+	limit  int64
+	size   int64
+}
+
+// Copy the source range into the destination, a regular file is replaced only after successful copy.
+func copyToDestination(ctx context.Context, o *options, sum *checksummer, src *copySource, toPath string) error {
+	toFile, temp, err := openDestination(toPath)
 	if err != nil {
-		if errors.Is(err, io.EOF) {
-			logger.Info("EOF reached")
+		o.logger.Error("Error opening destination", "error", err)
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			abandonDestination(o, toFile, toPath, temp)
+		}
+	}()
+	o.logger.Info("Opened destination", "path", toFile.Name())
+
+	// Set offset if needed
+	if src.offset > 0 {
+		if src.stream {
+			err = skipOffset(src.reader, src.offset)
 		} else {
-			logger.Error("Error during file copy", "error", err)
-			errRemove := os.Remove(toPath)
-			if errRemove != nil {
-				logger.Error("Error removing destination file", "error", errRemove)
-			}
+			_, err = src.file.Seek(src.offset, io.SeekStart)
+		}
+		if err != nil {
+			o.logger.Error("Error setting file offset", "error", err)
+			return err
+		}
+		o.logger.Info("Set file offset", "offset", src.offset)
+	}
+
+	o.progress.Start(src.size)
+	written, err := copyData(ctx, o, sum, src, toFile, temp)
+	if err != nil {
+		switch {
+		case errors.Is(err, io.EOF):
+			o.logger.Info("EOF reached")
+		case ctx.Err() != nil:
+			o.logger.Warn("Copy cancelled", "error", err)
+			return err
+		default:
+			o.logger.Error("Error during file copy", "error", err)
+			return err
+		}
+	}
+
+	if err := sum.check(toFile, written); err != nil {
+		o.logger.Error("Error verifying destination file", "error", err)
+		return err
+	}
+	if err := commitDestination(toFile, toPath, temp); err != nil {
+		o.logger.Error("Error replacing destination file", "error", err)
+		return err
+	}
+	committed = true
+
+	o.progress.Finish()
+	sum.done()
+	o.logger.Info("File copied successfully", "from", src.path, "to", toPath)
+
+	return nil
+}
+
+// Copy the source range in kernel or by chunks concurrently if nothing needs to see the bytes
+// in order, otherwise through the compression or by the loop. Returns the number of written bytes.
+func copyData(
+	ctx context.Context, o *options, sum *checksummer, src *copySource, toFile *os.File, temp bool,
+) (int64, error) {
+	if sum == nil && o.rate <= 0 && !o.transforms() && !src.stream && temp {
+		if o.workers > 1 {
+			from := &contextReaderAt{ctx: ctx, r: src.file}
+			return src.size, copyParallel(toFile, from, src.offset, src.size, DefaultChunkSize, o.workers, o.progress.Add)
+		}
+		if direct, err := copyFast(ctx, toFile, src.file, src.offset, src.size, o.progress.Add); direct {
+			return src.size, err
+		}
+	}
+
+	reader := &progressReader{r: o.throttle(src.reader), progress: o.progress.Add}
+	switch {
+	case o.transforms():
+		return copyTransformed(toFile, reader, src.limit, o, sum)
+	case src.limit == 0:
+		return io.Copy(toFile, sum.tee(reader))
+	default:
+		return io.CopyN(toFile, sum.tee(reader), src.limit)
+	}
+}
+
+// Copy into the partial file next to the destination, keeping it on failure to resume later.
+// The destination is replaced by the partial file when it is complete.
+func copyResumable(
+	ctx context.Context, fromFile *os.File, fromPath, toPath string,
+	offset, limit, sizeToCopy int64, o *options, sum *checksummer,
+) error {
+	partPath := toPath + PartialSuffix
+	toFile, cp, err := openResumable(fromFile, fromPath, partPath, offset, limit, sizeToCopy, o.logger)
+	if err != nil {
+		o.logger.Error("Error opening partial file for resume", "error", err)
+		return err
+	}
+	defer toFile.Close()
+	if cp.Copied > 0 {
+		o.logger.Info("Resuming copy", "path", partPath, "copied", cp.Copied)
+	}
+
+	// The verified prefix is not read again, so hash it separately
+	if sum != nil {
+		if _, err := io.Copy(sum.hash, io.NewSectionReader(fromFile, offset, cp.Copied)); err != nil {
+			o.logger.Error("Error hashing copied part", "error", err)
 			return err
 		}
 	}
 
-	bar.Finish()
-	logger.Info("File copied successfully", "from", fromPath, "to", toPath)
+	o.progress.Start(sizeToCopy)
+	o.progress.SetCurrent(cp.Copied)
+	source := &contextReader{ctx: ctx, r: fromFile}
+	barReader := sum.tee(&progressReader{r: o.throttle(source), progress: o.progress.Add})
+
+	if err := copyWithCheckpoints(toFile, barReader, cp, sizeToCopy, partPath); err != nil {
+		o.logger.Error("Error during file copy, partial file is kept", "error", err, "path", partPath)
+		return err
+	}
+	written, err := toFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		o.logger.Error("Error getting partial file size", "error", err)
+		return err
+	}
+	if err := sum.check(toFile, written); err != nil {
+		o.logger.Error("Error verifying partial file", "error", err)
+		return err
+	}
+
+	if err := commitFile(toFile, toPath); err != nil {
+		o.logger.Error("Error replacing destination file", "error", err)
+		return err
+	}
+	if err := os.Remove(checkpointPath(partPath)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		o.logger.Error("Error removing checkpoint", "error", err)
+		return err
+	}
+
+	o.progress.Finish()
+	sum.done()
+	o.logger.Info("File copied successfully", "from", fromPath, "to", toPath)
 
 	return nil
 }
@@ -0,0 +1,102 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// MaxCopyRange limits a single copy_file_range call to check cancellation in between.
+const MaxCopyRange = 64 << 20
+
+// Copy size bytes from offset of the source to the new destination file in kernel.
+// The whole file is cloned if the filesystem supports reflinks, otherwise only data
+// segments are copied and holes are kept. It reports whether the fast path was used.
+func copyFast(ctx context.Context, dst, src *os.File, offset, size int64, progress func(int64)) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return true, err
+	}
+	srcFd, dstFd := int(src.Fd()), int(dst.Fd())
+
+	if offset == 0 {
+		if info, err := src.Stat(); err == nil && info.Size() == size {
+			if err := unix.IoctlFileClone(dstFd, srcFd); err == nil {
+				progress(size)
+				return true, nil
+			}
+		}
+	}
+
+	end := offset + size
+	for pos := offset; pos < end; {
+		start, stop, err := nextData(srcFd, pos, end)
+		if err != nil {
+			return true, err
+		}
+		progress(start - pos)
+		if err := copyRange(ctx, dst, src, start, start-offset, stop-start, progress); err != nil {
+			return true, err
+		}
+		pos = stop
+	}
+
+	// Trailing hole is made by extending the file
+	return true, dst.Truncate(size)
+}
+
+// Find the next data segment of the file in [pos, end).
+// Without SEEK_DATA support the whole range is data.
+func nextData(fd int, pos, end int64) (int64, int64, error) {
+	start, err := unix.Seek(fd, pos, unix.SEEK_DATA)
+	switch {
+	case errors.Is(err, unix.ENXIO):
+		return end, end, nil
+	case errors.Is(err, unix.EINVAL), errors.Is(err, unix.EOPNOTSUPP):
+		return pos, end, nil
+	case err != nil:
+		return 0, 0, err
+	}
+	if start >= end {
+		return end, end, nil
+	}
+	stop, err := unix.Seek(fd, start, unix.SEEK_HOLE)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, min(stop, end), nil
+}
+
+// Copy n bytes between the offsets with copy_file_range, falling back to read and write
+// when it is not supported for the files, e.g. on different filesystems.
+// The context is checked between calls.
+func copyRange(ctx context.Context, dst, src *os.File, srcOff, dstOff, n int64, progress func(int64)) error {
+	for n > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		copied, err := unix.CopyFileRange(int(src.Fd()), &srcOff, int(dst.Fd()), &dstOff, int(min(n, MaxCopyRange)), 0)
+		if errors.Is(err, unix.EXDEV) || errors.Is(err, unix.ENOSYS) ||
+			errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EINVAL) {
+			section := &contextReader{ctx: ctx, r: io.NewSectionReader(src, srcOff, n)}
+			r := &progressReader{r: section, progress: progress}
+			_, err = io.Copy(io.NewOffsetWriter(dst, dstOff), r)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if copied == 0 {
+			return io.ErrUnexpectedEOF
+		}
+		srcOff += int64(copied)
+		dstOff += int64(copied)
+		n -= int64(copied)
+		progress(int64(copied))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const sparseSize = 8 << 20
+
+// Create sparse file with two data segments and return its content.
+func makeSparse(t *testing.T) (string, []byte) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sparse.img")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, f.Truncate(sparseSize))
+
+	content := make([]byte, sparseSize)
+	for _, off := range []int64{1 << 20, 5 << 20} {
+		data := bytes.Repeat([]byte("data"), 1024)
+		_, err := f.WriteAt(data, off)
+		require.NoError(t, err)
+		copy(content[off:], data)
+	}
+	return path, content
+}
+
+// Get allocated size of the file.
+func allocated(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	return info.Sys().(*syscall.Stat_t).Blocks * 512
+}
+
+func TestCopySparse(t *testing.T) {
+	fromPath, content := makeSparse(t)
+	if allocated(t, fromPath) >= sparseSize {
+		t.Skip("filesystem doesn't support sparse files")
+	}
+
+	tests := []struct {
+		name   string
+		offset int64
+		limit  int64
+	}{
+		{name: "whole file", offset: 0, limit: 0},
+		{name: "range", offset: 512 << 10, limit: 5 << 20},
+		{name: "trailing hole", offset: 6 << 20, limit: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toPath := filepath.Join(t.TempDir(), "out.img")
+			err := Copy(fromPath, toPath, tt.offset, tt.limit)
+			require.NoError(t, err)
+
+			end := int64(sparseSize)
+			if tt.limit > 0 {
+				end = tt.offset + tt.limit
+			}
+			data, err := os.ReadFile(toPath)
+			require.NoError(t, err)
+			require.True(t, bytes.Equal(content[tt.offset:end], data), "file contents are not equal")
+			require.Less(t, allocated(t, toPath), end-tt.offset, "holes are not kept")
+		})
+	}
+}
+
+func TestCopyTreeSparse(t *testing.T) {
+	fromPath, content := makeSparse(t)
+	if allocated(t, fromPath) >= sparseSize {
+		t.Skip("filesystem doesn't support sparse files")
+	}
+	toDir := filepath.Join(t.TempDir(), "dst")
+
+	err := CopyTree(filepath.Dir(fromPath), toDir)
+	require.NoError(t, err)
+
+	toPath := filepath.Join(toDir, filepath.Base(fromPath))
+	data, err := os.ReadFile(toPath)
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(content, data), "file contents are not equal")
+	require.Less(t, allocated(t, toPath), int64(sparseSize), "holes are not kept")
+}
+
+func TestNextData(t *testing.T) {
+	fromPath, _ := makeSparse(t)
+	if allocated(t, fromPath) >= sparseSize {
+		t.Skip("filesystem doesn't support sparse files")
+	}
+	f, err := os.Open(fromPath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	start, stop, err := nextData(int(f.Fd()), 0, sparseSize)
+	require.NoError(t, err)
+	require.LessOrEqual(t, start, int64(1<<20))
+	require.Greater(t, stop, int64(1<<20))
+	require.Less(t, stop, int64(5<<20))
+
+	start, stop, err = nextData(int(f.Fd()), 6<<20, sparseSize)
+	require.NoError(t, err)
+	require.Equal(t, int64(sparseSize), start)
+	require.Equal(t, int64(sparseSize), stop)
+}
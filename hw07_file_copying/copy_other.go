@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"os"
+)
+
+// Fast path is implemented only on Linux, the copy loop is used elsewhere.
+func copyFast(_ context.Context, _, _ *os.File, _, _ int64, _ func(int64)) (bool, error) {
+	return false, nil
+}
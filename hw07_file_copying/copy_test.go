@@ -2,7 +2,6 @@ package main
 
 import (
 	"bytes"
-	"errors"
 	"os"
 	"testing"
 
@@ -202,7 +201,7 @@ func TestCheckArgs(t *testing.T) {
 			to:          "testdata/tmp/out_offset0_limit0.txt",
 			offset:      0,
 			limit:       0,
-			expectedErr: errors.New("source file path is empty"),
+			expectedErr: ErrEmptySource,
 		},
 		{
 			name:        "empty destination file path",
@@ -210,15 +209,23 @@ func TestCheckArgs(t *testing.T) {
 			to:          "",
 			offset:      0,
 			limit:       0,
-			expectedErr: errors.New("destination file path is empty"),
+			expectedErr: ErrEmptyDestination,
 		},
 		{
-			name:        "negative offset",
+			name:        "same paths",
+			from:        "testdata/input.txt",
+			to:          "testdata/../testdata/input.txt",
+			offset:      0,
+			limit:       0,
+			expectedErr: ErrSamePaths,
+		},
+		{
+			name:        "tail offset",
 			from:        "testdata/input.txt",
 			to:          "testdata/tmp/out_offset0_limit0.txt",
 			offset:      -1,
 			limit:       0,
-			expectedErr: errors.New("offset cannot be negative"),
+			expectedErr: nil,
 		},
 		{
 			name:        "negative limit",
@@ -226,7 +233,7 @@ func TestCheckArgs(t *testing.T) {
 			to:          "testdata/tmp/out_offset0_limit0.txt",
 			offset:      0,
 			limit:       -1,
-			expectedErr: errors.New("limit cannot be negative"),
+			expectedErr: ErrNegativeLimit,
 		},
 	}
 
@@ -2,7 +2,12 @@ module github.com/MaksimIschenko/hw_otus_golang/hw07_file_copying
 
 go 1.22.1
 
-require github.com/cheggaaa/pb/v3 v3.1.6
+require (
+	github.com/cheggaaa/pb/v3 v3.1.6
+	github.com/klauspost/compress v1.18.0
+	github.com/stretchr/testify v1.10.0
+	golang.org/x/sys v0.29.0
+)
 
 require (
 	github.com/VividCortex/ewma v1.2.0 // indirect
@@ -13,7 +18,5 @@ require (
 	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	github.com/stretchr/testify v1.10.0 // indirect
-	golang.org/x/sys v0.29.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
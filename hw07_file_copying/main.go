@@ -1,35 +1,281 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 var (
 	from, to      string
 	limit, offset int64
+	byteRange     string
+	resume        bool
+	checksum      string
+	checksumFile  string
+	verify        bool
+	recursive     bool
+	symlinks      string
+	existing      string
+	workers       int
+	rate          string
+	progress      string
+	logFormat     string
+	quiet         bool
+	compress      string
+	decompress    bool
+	keepPartial   bool
 )
 
 var logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 
 func init() {
-	flag.StringVar(&from, "from", "", "file to read from")
-	flag.StringVar(&to, "to", "", "file to write to")
+	flag.StringVar(&from, "from", "", "file to read from, - for stdin")
+	flag.StringVar(&to, "to", "", "file to write to, - for stdout")
 	flag.Int64Var(&limit, "limit", 0, "limit of bytes to copy")
-	flag.Int64Var(&offset, "offset", 0, "offset in input file")
+	flag.Int64Var(&offset, "offset", 0, "offset in input file, negative offset is counted from the end")
+	flag.StringVar(&byteRange, "range", "", "bytes to copy instead of offset and limit: start-end, start- or -n")
+	flag.BoolVar(&resume, "resume", false, "continue an interrupted copy into existing destination")
+	flag.StringVar(&checksum, "checksum", "", "compute digest of copied bytes: sha256, md5 or crc32c")
+	flag.StringVar(&checksumFile, "checksum-file", "", "file to write digest to instead of printing it")
+	flag.BoolVar(&verify, "verify", false, "verify destination digest after write (requires -checksum)")
+	flag.StringVar(&compress, "compress", "", "compress copied bytes: gzip or zstd")
+	flag.BoolVar(&decompress, "decompress", false, "decompress copied bytes, gzip or zstd is detected")
+	flag.IntVar(&workers, "workers", 1, "number of workers copying file chunks concurrently")
+	flag.StringVar(&rate, "rate", "", "limit copy bandwidth, e.g. 10MB/s or 512KiB/s")
+	flag.StringVar(&progress, "progress", ProgressBar, "progress on stderr: bar, json or none")
+	flag.StringVar(&logFormat, "log-format", "text", "log format: text or json")
+	flag.BoolVar(&quiet, "quiet", false, "log errors only")
+	flag.BoolVar(&keepPartial, "keep-partial", false, "keep partial destination on failure or interrupt to resume it")
+	flag.BoolVar(&recursive, "recursive", false, "copy directory recursively")
+	flag.StringVar(&symlinks, "symlinks", "preserve", "symbolic links in recursive mode: preserve or follow")
+	flag.StringVar(&existing, "existing", "overwrite", "existing files in recursive mode: overwrite or skip")
+}
+
+// Exit codes of error classes.
+const (
+	ExitIOError               = 1
+	ExitBadArgs               = 2
+	ExitUnsupportedFile       = 3
+	ExitOffsetExceedsFileSize = 4
+	ExitCancelled             = 130
+	ExitTerminated            = 143
+)
+
+var (
+	ErrConflictingArgs      = errors.New("conflicting arguments")
+	ErrUnsupportedLogFormat = errors.New("unsupported log format")
+	ErrInterrupted          = errors.New("interrupted")
+	ErrTerminated           = errors.New("terminated")
+)
+
+// Errors of invalid arguments.
+var badArgsErrors = []error{
+	ErrEmptySource, ErrEmptyDestination, ErrSamePaths, ErrNegativeLimit,
+	ErrConflictingArgs, ErrUnsupportedLogFormat, ErrInvalidRange, ErrInvalidRate,
+	ErrUnsupportedProgress, ErrUnsupportedChecksum, ErrUnsupportedCompression, ErrUnsupportedPolicy,
+	ErrUnsupportedStream, ErrUnsupportedTreeOption, ErrNestedDestination,
 }
 
 func main() {
 	flag.Parse()
 
+	// Interrupt cancels the copy, so the partial destination is handled
+	ctx, stop := notifyContext(context.Background())
+	err := run(ctx)
+	if errors.Is(err, context.Canceled) {
+		err = fmt.Errorf("%w: %w", err, context.Cause(ctx))
+	}
+	stop()
+	if err != nil {
+		logger.Error(fmt.Sprintf("%v", err))
+		os.Exit(exitCode(err))
+	}
+}
+
+// Cancel the context on SIGINT or SIGTERM with the error of the signal as the cause.
+func notifyContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			if sig == syscall.SIGTERM {
+				cancel(ErrTerminated)
+			} else {
+				cancel(ErrInterrupted)
+			}
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(context.Canceled)
+	}
+}
+
+// Get exit code of the error class. Termination is reported as 128+SIGTERM,
+// interrupt and any other cancellation as 128+SIGINT.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrTerminated):
+		return ExitTerminated
+	case errors.Is(err, context.Canceled):
+		return ExitCancelled
+	case errors.Is(err, ErrOffsetExceedsFileSize):
+		return ExitOffsetExceedsFileSize
+	case errors.Is(err, ErrUnsupportedFile), errors.Is(err, ErrUnknownFormat), errors.Is(err, ErrSymlinkLoop):
+		return ExitUnsupportedFile
+	}
+	for _, badArgs := range badArgsErrors {
+		if errors.Is(err, badArgs) {
+			return ExitBadArgs
+		}
+	}
+	return ExitIOError
+}
+
+// Copy with arguments from flags.
+func run(ctx context.Context) error {
+	l, err := newLogger(logFormat, quiet)
+	if err != nil {
+		return err
+	}
+	logger = l
+
 	logger.Info("Starting copyfile")
 
-	err := Copy(from, to, offset, limit)
+	if byteRange != "" {
+		if offset != 0 || limit != 0 {
+			return fmt.Errorf("%w: range can't be used with offset or limit", ErrConflictingArgs)
+		}
+		if offset, limit, err = ParseRange(byteRange); err != nil {
+			return err
+		}
+	}
+
+	// The digest is reported after the copy, its error fails the run
+	var reportErr error
+	opts, err := optionsFromFlags(func(digest string) {
+		reportErr = reportDigest(digest)
+	})
 	if err != nil {
-		logger.Error(fmt.Sprintf("%v", err))
+		return err
+	}
+	if recursive {
+		err = copyTree(ctx, opts)
+	} else {
+		err = CopyContext(ctx, from, to, offset, limit, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	if reportErr != nil {
+		return reportErr
 	}
 
 	logger.Info("Finishing copyfile")
+	return nil
+}
+
+// Get Copy options from flags, the digest is passed to report.
+func optionsFromFlags(report func(digest string)) ([]Option, error) {
+	p, err := NewProgress(progress, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	opts := []Option{WithLogger(logger), WithProgress(p)}
+	if resume {
+		opts = append(opts, WithResume())
+	}
+	if checksum != "" {
+		opts = append(opts, WithChecksum(checksum, report))
+	}
+	if verify {
+		opts = append(opts, WithVerify())
+	}
+	if compress != "" {
+		opts = append(opts, WithCompress(compress))
+	}
+	if decompress {
+		opts = append(opts, WithDecompress())
+	}
+	if keepPartial {
+		opts = append(opts, WithKeepPartial())
+	}
+	if workers > 1 {
+		opts = append(opts, WithWorkers(workers))
+	}
+	if rate != "" {
+		bytesPerSecond, err := ParseRate(rate)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithRate(bytesPerSecond))
+	}
+	return opts, nil
+}
+
+// Create logger of the format, only errors are logged in quiet mode.
+// Stdout is kept for the copied data when it is the destination.
+func newLogger(format string, quiet bool) (*slog.Logger, error) {
+	out := os.Stdout
+	if to == StdStream {
+		out = os.Stderr
+	}
+	level := slog.LevelInfo
+	if quiet {
+		level = slog.LevelError
+	}
+	handlerOptions := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(out, handlerOptions)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(out, handlerOptions)), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedLogFormat, format)
+	}
+}
+
+// Copy directory with the policies from flags.
+func copyTree(ctx context.Context, opts []Option) error {
+	if offset != 0 || limit != 0 {
+		return fmt.Errorf("%w: offset and limit are not supported in recursive mode", ErrConflictingArgs)
+	}
+	symlinkPolicy, err := ParseSymlinkPolicy(symlinks)
+	if err != nil {
+		return err
+	}
+	existingPolicy, err := ParseExistingPolicy(existing)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, WithSymlinks(symlinkPolicy), WithExisting(existingPolicy))
+	return CopyTreeContext(ctx, from, to, opts...)
+}
+
+// Print digest or write it to the checksum file.
+func reportDigest(digest string) error {
+	if checksumFile == "" {
+		out := os.Stdout
+		if to == StdStream {
+			out = os.Stderr
+		}
+		_, err := fmt.Fprintf(out, "%s  %s\n", digest, to)
+		return err
+	}
+	if err := writeChecksumFile(checksumFile, digest, to); err != nil {
+		return fmt.Errorf("writing checksum file: %w", err)
+	}
+	return nil
 }
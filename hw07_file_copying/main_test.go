@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "no error", err: nil, expected: 0},
+		{name: "empty source", err: ErrEmptySource, expected: ExitBadArgs},
+		{name: "same paths", err: ErrSamePaths, expected: ExitBadArgs},
+		{name: "invalid range", err: fmt.Errorf("%w: %q", ErrInvalidRange, "x"), expected: ExitBadArgs},
+		{name: "conflicting args", err: ErrConflictingArgs, expected: ExitBadArgs},
+		{name: "unsupported file", err: ErrUnsupportedFile, expected: ExitUnsupportedFile},
+		{name: "unknown format", err: ErrUnknownFormat, expected: ExitUnsupportedFile},
+		{name: "offset exceeds file size", err: ErrOffsetExceedsFileSize, expected: ExitOffsetExceedsFileSize},
+		{name: "not found", err: &fs.PathError{Op: "open", Path: "x", Err: fs.ErrNotExist}, expected: ExitIOError},
+		{name: "other", err: errors.New("disk is full"), expected: ExitIOError},
+		{name: "cancelled", err: context.Canceled, expected: ExitCancelled},
+		{name: "interrupted", err: fmt.Errorf("%w: %w", context.Canceled, ErrInterrupted), expected: ExitCancelled},
+		{name: "terminated", err: fmt.Errorf("%w: %w", context.Canceled, ErrTerminated), expected: ExitTerminated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, exitCode(tt.err))
+		})
+	}
+}
+
+func TestExitCodeOfCopy(t *testing.T) {
+	require.Equal(t, ExitBadArgs, exitCode(Copy("", "out.txt", 0, 0)))
+	require.Equal(t, ExitUnsupportedFile, exitCode(Copy("testdata", "out.txt", 0, 0)))
+	require.Equal(t, ExitOffsetExceedsFileSize, exitCode(Copy("testdata/input.txt", "out.txt", 10000, 0)))
+	require.Equal(t, ExitIOError, exitCode(Copy("testdata/notfound.txt", "out.txt", 0, 0)))
+}
+
+func TestNotifyContext(t *testing.T) {
+	ctx, stop := notifyContext(context.Background())
+	defer stop()
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+	<-ctx.Done()
+	require.ErrorIs(t, context.Cause(ctx), ErrTerminated)
+}
+
+func TestReportDigestError(t *testing.T) {
+	saved := checksumFile
+	t.Cleanup(func() {
+		checksumFile = saved
+	})
+	checksumFile = filepath.Join(t.TempDir(), "missing", "out.txt.sha256")
+
+	err := reportDigest("abc123")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	require.Equal(t, ExitIOError, exitCode(err))
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	DefaultChunkSize = 8 << 20
+	CopyBufferSize   = 256 << 10
+)
+
+// WithWorkers makes Copy split the range into chunks and copy them by n workers concurrently.
+// Chunks are written out of order, so it can't be combined with checksum, rate, compression,
+// resume, streams or destinations written in place.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// Check nothing needs to see the copied bytes in order and both files can be seeked.
+func checkWorkers(o *options, stream bool, toPath string) error {
+	if o.workers <= 1 {
+		return nil
+	}
+	if o.checksum != "" || o.rate > 0 || o.transforms() || o.resume || stream || isInPlace(toPath) {
+		return fmt.Errorf("%w: workers need regular files and no checksum, rate, compression or resume",
+			ErrConflictingArgs)
+	}
+	return nil
+}
+
+// Copy size bytes from offset of the source to the start of the destination by chunks
+// concurrently. The first error stops handing out chunks and is returned.
+func copyParallel(
+	dst io.WriterAt, src io.ReaderAt, offset, size, chunkSize int64, workers int, progress func(int64),
+) error {
+	chunks := make(chan int64)
+	stop := make(chan struct{})
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, min(CopyBufferSize, chunkSize))
+			for start := range chunks {
+				n := min(chunkSize, size-start)
+				if err := copyChunk(dst, src, offset+start, start, n, buf, progress); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(stop)
+					})
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for start := int64(0); start < size; start += chunkSize {
+		select {
+		case chunks <- start:
+		case <-stop:
+			break feed
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	return firstErr
+}
+
+// Copy n bytes between the offsets through the buffer.
+func copyChunk(dst io.WriterAt, src io.ReaderAt, srcOff, dstOff, n int64, buf []byte, progress func(int64)) error {
+	for n > 0 {
+		read, err := src.ReadAt(buf[:min(int64(len(buf)), n)], srcOff)
+		if read > 0 {
+			if _, err := dst.WriteAt(buf[:read], dstOff); err != nil {
+				return err
+			}
+			srcOff += int64(read)
+			dstOff += int64(read)
+			n -= int64(read)
+			progress(int64(read))
+		}
+		if errors.Is(err, io.EOF) && n > 0 {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// WriterAt failing after the first write.
+type failingWriterAt struct {
+	writes atomic.Int32
+}
+
+var errWrite = errors.New("write error")
+
+func (w *failingWriterAt) WriteAt(p []byte, _ int64) (int, error) {
+	if w.writes.Add(1) > 1 {
+		return 0, errWrite
+	}
+	return len(p), nil
+}
+
+func TestCopyParallel(t *testing.T) {
+	data := make([]byte, 1<<20+123)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	tests := []struct {
+		name      string
+		offset    int64
+		size      int64
+		chunkSize int64
+		workers   int
+	}{
+		{name: "whole", offset: 0, size: int64(len(data)), chunkSize: 64 << 10, workers: 4},
+		{name: "range", offset: 1000, size: 500 << 10, chunkSize: 10000, workers: 3},
+		{name: "more workers than chunks", offset: 10, size: 100, chunkSize: 64, workers: 8},
+		{name: "single chunk", offset: 0, size: 100, chunkSize: 1 << 20, workers: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst, err := os.Create(filepath.Join(t.TempDir(), "out"))
+			require.NoError(t, err)
+			defer dst.Close()
+
+			var progress atomic.Int64
+			err = copyParallel(dst, bytes.NewReader(data), tt.offset, tt.size, tt.chunkSize, tt.workers,
+				func(n int64) { progress.Add(n) })
+			require.NoError(t, err)
+			require.Equal(t, tt.size, progress.Load())
+
+			copied, err := os.ReadFile(dst.Name())
+			require.NoError(t, err)
+			require.True(t, bytes.Equal(data[tt.offset:tt.offset+tt.size], copied), "contents are not equal")
+		})
+	}
+
+	t.Run("write error", func(t *testing.T) {
+		err := copyParallel(&failingWriterAt{}, bytes.NewReader(data), 0, int64(len(data)), 1024, 4, func(int64) {})
+		require.ErrorIs(t, err, errWrite)
+	})
+
+	t.Run("source too short", func(t *testing.T) {
+		err := copyParallel(&failingWriterAt{}, bytes.NewReader(data[:100]), 0, 200, 1024, 2, func(int64) {})
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
+
+func TestCopyWorkers(t *testing.T) {
+	toPath := filepath.Join(t.TempDir(), "out.txt")
+
+	err := Copy("testdata/input.txt", toPath, 100, 1000, WithWorkers(4))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(toPath)
+	require.NoError(t, err)
+	correctData, err := os.ReadFile("testdata/out_offset100_limit1000.txt")
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(data, correctData), "file contents are not equal")
+}
+
+func TestCopyWorkersConflicts(t *testing.T) {
+	toPath := filepath.Join(t.TempDir(), "out.txt")
+
+	tests := []struct {
+		name     string
+		fromPath string
+		opts     []Option
+	}{
+		{name: "checksum", fromPath: "testdata/input.txt", opts: []Option{WithChecksum(ChecksumSHA256, nil)}},
+		{name: "rate", fromPath: "testdata/input.txt", opts: []Option{WithRate(1024)}},
+		{name: "compress", fromPath: "testdata/input.txt", opts: []Option{WithCompress(CompressGzip)}},
+		{name: "resume", fromPath: "testdata/input.txt", opts: []Option{WithResume()}},
+		{name: "stream", fromPath: "/dev/zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Copy(tt.fromPath, toPath, 0, 100, append(tt.opts, WithWorkers(4))...)
+			require.ErrorIs(t, err, ErrConflictingArgs)
+
+			_, err = os.Stat(toPath)
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
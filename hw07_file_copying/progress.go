@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	pb "github.com/cheggaaa/pb/v3"
+)
+
+const (
+	BarWidth = 100
+
+	ProgressBar  = "bar"
+	ProgressJSON = "json"
+	ProgressNone = "none"
+
+	DefaultProgressInterval = time.Second
+)
+
+var ErrUnsupportedProgress = errors.New("unsupported progress")
+
+// Progress reports the number of copied bytes. Add may be called concurrently.
+type Progress interface {
+	// Start reporting, total is unknown if it is not positive.
+	Start(total int64)
+	Add(n int64)
+	SetCurrent(n int64)
+	Finish()
+}
+
+// NewProgress creates progress of the kind: bar, json or none.
+func NewProgress(kind string, w io.Writer) (Progress, error) {
+	switch kind {
+	case ProgressBar:
+		return &barProgress{w: w}, nil
+	case ProgressJSON:
+		return newJSONProgress(w, realClock{}, DefaultProgressInterval), nil
+	case ProgressNone:
+		return noProgress{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedProgress, kind)
+	}
+}
+
+// WithProgress sets progress reporting, nothing is reported by default.
+func WithProgress(p Progress) Option {
+	return func(o *options) {
+		o.progress = p
+	}
+}
+
+// Reader reporting the number of bytes read.
+type progressReader struct {
+	r        io.Reader
+	progress func(int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.progress(int64(n))
+	return n, err
+}
+
+// Progress bar for terminal.
+type barProgress struct {
+	w   io.Writer
+	bar *pb.ProgressBar
+}
+
+// Without total only copied bytes and elapsed time are shown.
+func (p *barProgress) Start(total int64) {
+	tmpl := `{{ bar . "[" "=" ">" " " "]"}} {{counters .}}`
+	if total <= 0 {
+		tmpl = `{{ cycle . "-" "\\" "|" "/" }} {{counters .}} {{etime .}}`
+	}
+	p.bar = pb.ProgressBarTemplate(tmpl).New(0).SetTotal(total).SetWriter(p.w).SetMaxWidth(BarWidth).Start()
+}
+
+func (p *barProgress) Add(n int64) { p.bar.Add64(n) }
+
+func (p *barProgress) SetCurrent(n int64) { p.bar.SetCurrent(n) }
+
+func (p *barProgress) Finish() { p.bar.Finish() }
+
+// ProgressEvent is written by json progress as a line.
+// Rate is the average since start in bytes per second, ETA is in seconds.
+type ProgressEvent struct {
+	Bytes int64    `json:"bytes"`
+	Total int64    `json:"total,omitempty"`
+	Rate  float64  `json:"rate"`
+	ETA   *float64 `json:"eta,omitempty"`
+	Done  bool     `json:"done"`
+}
+
+// Progress writing events not more often than interval and when finished.
+type jsonProgress struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	clock    Clock
+	interval time.Duration
+	total    int64
+	current  int64
+	base     int64
+	start    time.Time
+	last     time.Time
+}
+
+func newJSONProgress(w io.Writer, clock Clock, interval time.Duration) *jsonProgress {
+	return &jsonProgress{enc: json.NewEncoder(w), clock: clock, interval: interval}
+}
+
+func (p *jsonProgress) Start(total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = total
+	p.start = p.clock.Now()
+	p.emit(p.start, false)
+}
+
+func (p *jsonProgress) Add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current += n
+	if now := p.clock.Now(); now.Sub(p.last) >= p.interval {
+		p.emit(now, false)
+	}
+}
+
+// Bytes set before copying, e.g. on resume, are not counted in the rate.
+func (p *jsonProgress) SetCurrent(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = n
+	p.base = n
+}
+
+func (p *jsonProgress) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.emit(p.clock.Now(), true)
+}
+
+// Write event, it is best effort and errors are ignored.
+func (p *jsonProgress) emit(now time.Time, done bool) {
+	p.last = now
+	event := ProgressEvent{Bytes: p.current, Done: done}
+	if p.total > 0 {
+		event.Total = p.total
+	}
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 {
+		event.Rate = float64(p.current-p.base) / elapsed
+	}
+	if p.total > 0 && event.Rate > 0 {
+		eta := float64(max(p.total-p.current, 0)) / event.Rate
+		event.ETA = &eta
+	}
+	_ = p.enc.Encode(event)
+}
+
+// Progress reporting nothing.
+type noProgress struct{}
+
+func (noProgress) Start(int64) {}
+
+func (noProgress) Add(int64) {}
+
+func (noProgress) SetCurrent(int64) {}
+
+func (noProgress) Finish() {}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Decode json progress events.
+func readEvents(t *testing.T, buf *bytes.Buffer) []ProgressEvent {
+	t.Helper()
+	var events []ProgressEvent
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var event ProgressEvent
+		require.NoError(t, dec.Decode(&event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestJSONProgress(t *testing.T) {
+	t.Run("events", func(t *testing.T) {
+		var buf bytes.Buffer
+		clock := &fakeClock{}
+		p := newJSONProgress(&buf, clock, time.Second)
+
+		p.Start(1000)
+		p.Add(100)
+		clock.Sleep(time.Second)
+		p.Add(100)
+		clock.Sleep(time.Second / 2)
+		p.Add(300)
+		p.Finish()
+
+		events := readEvents(t, &buf)
+		require.Len(t, events, 3)
+		require.Equal(t, ProgressEvent{Bytes: 0, Total: 1000}, events[0])
+		require.Equal(t, ProgressEvent{Bytes: 200, Total: 1000, Rate: 200, ETA: floatPtr(4)}, events[1])
+
+		last := events[2]
+		require.Equal(t, int64(500), last.Bytes)
+		require.True(t, last.Done)
+		require.InDelta(t, 500/1.5, last.Rate, 1e-9)
+		require.NotNil(t, last.ETA)
+		require.InDelta(t, 1.5, *last.ETA, 1e-9)
+	})
+
+	t.Run("unknown total", func(t *testing.T) {
+		var buf bytes.Buffer
+		clock := &fakeClock{}
+		p := newJSONProgress(&buf, clock, time.Second)
+
+		p.Start(0)
+		clock.Sleep(2 * time.Second)
+		p.Add(100)
+		p.Finish()
+
+		events := readEvents(t, &buf)
+		require.Len(t, events, 3)
+		require.Equal(t, ProgressEvent{Bytes: 100, Rate: 50, Done: true}, events[2])
+	})
+
+	t.Run("resumed bytes are not in rate", func(t *testing.T) {
+		var buf bytes.Buffer
+		clock := &fakeClock{}
+		p := newJSONProgress(&buf, clock, time.Second)
+
+		p.Start(1000)
+		p.SetCurrent(600)
+		clock.Sleep(time.Second)
+		p.Add(100)
+		p.Finish()
+
+		events := readEvents(t, &buf)
+		require.Equal(t, int64(700), events[len(events)-1].Bytes)
+		require.Equal(t, float64(100), events[len(events)-1].Rate)
+	})
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestNewProgress(t *testing.T) {
+	var buf bytes.Buffer
+
+	p, err := NewProgress(ProgressBar, &buf)
+	require.NoError(t, err)
+	p.Start(100)
+	p.Add(100)
+	p.Finish()
+	require.Contains(t, buf.String(), "100 / 100")
+
+	p, err = NewProgress(ProgressNone, &buf)
+	require.NoError(t, err)
+	require.Equal(t, noProgress{}, p)
+
+	_, err = NewProgress("spinner", &buf)
+	require.ErrorIs(t, err, ErrUnsupportedProgress)
+}
+
+func TestCopyProgress(t *testing.T) {
+	var buf bytes.Buffer
+	p, err := NewProgress(ProgressJSON, &buf)
+	require.NoError(t, err)
+
+	err = Copy("testdata/input.txt", filepath.Join(t.TempDir(), "out.txt"), 100, 1000, WithProgress(p))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var last ProgressEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+	require.Equal(t, int64(1000), last.Bytes)
+	require.Equal(t, int64(1000), last.Total)
+	require.True(t, last.Done)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidRange = errors.New("invalid range")
+
+// ParseRange parses byte range in the syntax of HTTP Range header: start-end with inclusive end,
+// start- up to the end of file or -n for the last n bytes. It returns offset and limit of Copy,
+// the offset of the last bytes is negative.
+func ParseRange(s string) (int64, int64, error) {
+	startStr, endStr, found := strings.Cut(strings.TrimPrefix(strings.TrimSpace(s), "bytes="), "-")
+	if !found {
+		return 0, 0, fmt.Errorf("%w: %q must be start-end, start- or -n", ErrInvalidRange, s)
+	}
+
+	if startStr == "" {
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, fmt.Errorf("%w: %q must end with positive number of bytes", ErrInvalidRange, s)
+		}
+		return -n, 0, nil
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, fmt.Errorf("%w: %q must start with non-negative offset", ErrInvalidRange, s)
+	}
+	if endStr == "" {
+		return start, 0, nil
+	}
+	end, err := strconv.ParseInt(endStr, 10, 64)
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("%w: %q must end with offset not less than start", ErrInvalidRange, s)
+	}
+	return start, end - start + 1, nil
+}
+
+// Resolve offset from the end of file of the size, the tail can't be larger than the file.
+func resolveOffset(size, offset int64) (int64, error) {
+	if offset >= 0 {
+		return offset, nil
+	}
+	if -offset > size {
+		return 0, fmt.Errorf("%w: tail offset %d is larger than file size %d", ErrOffsetExceedsFileSize, -offset, size)
+	}
+	return size + offset, nil
+}
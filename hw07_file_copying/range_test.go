@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		byteRange string
+		offset    int64
+		limit     int64
+	}{
+		{byteRange: "0-99", offset: 0, limit: 100},
+		{byteRange: "100-1099", offset: 100, limit: 1000},
+		{byteRange: "5-5", offset: 5, limit: 1},
+		{byteRange: "6000-", offset: 6000, limit: 0},
+		{byteRange: "-1000", offset: -1000, limit: 0},
+		{byteRange: "bytes=10-19", offset: 10, limit: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.byteRange, func(t *testing.T) {
+			offset, limit, err := ParseRange(tt.byteRange)
+			require.NoError(t, err)
+			require.Equal(t, tt.offset, offset)
+			require.Equal(t, tt.limit, limit)
+		})
+	}
+
+	for _, byteRange := range []string{"", "100", "-", "-0", "10-5", "a-b", "-5-10", "1-x"} {
+		t.Run("invalid "+byteRange, func(t *testing.T) {
+			_, _, err := ParseRange(byteRange)
+			require.ErrorIs(t, err, ErrInvalidRange)
+		})
+	}
+}
+
+func TestCopyTailOffset(t *testing.T) {
+	input, err := os.ReadFile("testdata/input.txt")
+	require.NoError(t, err)
+	size := int64(len(input))
+	toPath := filepath.Join(t.TempDir(), "out.txt")
+
+	tests := []struct {
+		name     string
+		offset   int64
+		limit    int64
+		expected []byte
+	}{
+		{name: "last bytes", offset: -1000, limit: 0, expected: input[size-1000:]},
+		{name: "last bytes with limit", offset: -1000, limit: 100, expected: input[size-1000 : size-900]},
+		{name: "whole file", offset: -size, limit: 0, expected: input},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Copy("testdata/input.txt", toPath, tt.offset, tt.limit)
+			require.NoError(t, err)
+
+			data, err := os.ReadFile(toPath)
+			require.NoError(t, err)
+			require.True(t, bytes.Equal(tt.expected, data), "file contents are not equal")
+		})
+	}
+
+	t.Run("larger than file", func(t *testing.T) {
+		err := Copy("testdata/input.txt", toPath, -size-1, 0)
+		require.ErrorIs(t, err, ErrOffsetExceedsFileSize)
+		require.ErrorContains(t, err, "tail offset")
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		err := Copy("/dev/zero", toPath, -10, 10)
+		require.ErrorIs(t, err, ErrUnsupportedStream)
+	})
+}
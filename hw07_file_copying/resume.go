@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+const (
+	CheckpointSuffix   = ".checkpoint"
+	CheckpointInterval = 1 << 20
+)
+
+// Checkpoint describes the part of a resumable copy which is flushed to the destination.
+type Checkpoint struct {
+	From   string `json:"from"`
+	Offset int64  `json:"offset"`
+	Limit  int64  `json:"limit"`
+	Copied int64  `json:"copied"`
+}
+
+// Get checkpoint file path for the destination.
+func checkpointPath(toPath string) string {
+	return toPath + CheckpointSuffix
+}
+
+// Read checkpoint if it exists.
+func readCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// Write checkpoint atomically.
+func writeCheckpoint(path string, cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil { //nolint:gosec
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Get SHA-256 of n bytes of the reader starting from offset.
+func hashRange(r io.ReaderAt, offset, n int64) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, offset, n)); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// Find the size of the partial destination prefix which matches the source.
+// The prefix is limited by the checkpoint, if any, and verified by checksum.
+func resumePosition(
+	fromFile, toFile *os.File, cp Checkpoint, toPath string, sizeToCopy int64, log *slog.Logger,
+) (int64, error) {
+	toInfo, err := toFile.Stat()
+	if err != nil {
+		return 0, err
+	}
+	copied := min(toInfo.Size(), sizeToCopy)
+
+	saved, err := readCheckpoint(checkpointPath(toPath))
+	if err != nil {
+		log.Warn("Ignoring unreadable checkpoint", "error", err)
+	}
+	if saved != nil {
+		if saved.From != cp.From || saved.Offset != cp.Offset || saved.Limit != cp.Limit {
+			log.Warn("Checkpoint belongs to another copy, starting over", "checkpoint", checkpointPath(toPath))
+			return 0, nil
+		}
+		copied = min(copied, saved.Copied)
+	}
+	if copied == 0 {
+		return 0, nil
+	}
+
+	toHash, err := hashRange(toFile, 0, copied)
+	if err != nil {
+		return 0, err
+	}
+	fromHash, err := hashRange(fromFile, cp.Offset, copied)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(toHash, fromHash) {
+		log.Warn("Partial destination does not match source, starting over", "path", toPath)
+		return 0, nil
+	}
+	return copied, nil
+}
+
+// Copy remaining bytes in chunks, flushing the destination and saving a checkpoint after each one.
+func copyWithCheckpoints(toFile *os.File, reader io.Reader, cp Checkpoint, sizeToCopy int64, toPath string) error {
+	for cp.Copied < sizeToCopy {
+		n, err := io.CopyN(toFile, reader, min(CheckpointInterval, sizeToCopy-cp.Copied))
+		cp.Copied += n
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if errSync := toFile.Sync(); errSync != nil {
+			return errSync
+		}
+		if errCp := writeCheckpoint(checkpointPath(toPath), cp); errCp != nil {
+			return errCp
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	return nil
+}
+
+// Open destination for resuming and position both files after the verified prefix.
+func openResumable(
+	fromFile *os.File, fromPath, toPath string, offset, limit, sizeToCopy int64, log *slog.Logger,
+) (*os.File, Checkpoint, error) {
+	absFrom, err := filepath.Abs(fromPath)
+	if err != nil {
+		return nil, Checkpoint{}, err
+	}
+	cp := Checkpoint{From: absFrom, Offset: offset, Limit: limit}
+
+	toFile, err := os.OpenFile(toPath, os.O_RDWR|os.O_CREATE, 0o644) //nolint:gosec
+	if err != nil {
+		return nil, cp, err
+	}
+
+	copied, err := resumePosition(fromFile, toFile, cp, toPath, sizeToCopy, log)
+	if err != nil {
+		toFile.Close()
+		return nil, cp, err
+	}
+	if err := toFile.Truncate(copied); err != nil {
+		toFile.Close()
+		return nil, cp, err
+	}
+	if _, err := toFile.Seek(copied, io.SeekStart); err != nil {
+		toFile.Close()
+		return nil, cp, err
+	}
+	if _, err := fromFile.Seek(offset+copied, io.SeekStart); err != nil {
+		toFile.Close()
+		return nil, cp, err
+	}
+	cp.Copied = copied
+	return toFile, cp, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyResume(t *testing.T) {
+	tests := []struct {
+		name        string
+		correctPath string
+		offset      int64
+		limit       int64
+		partial     func(correct []byte) []byte
+		checkpoint  *Checkpoint
+	}{
+		{
+			name:        "no destination",
+			correctPath: "testdata/out_offset0_limit0.txt",
+			partial:     nil,
+		},
+		{
+			name:        "partial destination",
+			correctPath: "testdata/out_offset0_limit0.txt",
+			partial:     func(correct []byte) []byte { return correct[:1000] },
+		},
+		{
+			name:        "partial destination with offset and limit",
+			correctPath: "testdata/out_offset100_limit1000.txt",
+			offset:      100,
+			limit:       1000,
+			partial:     func(correct []byte) []byte { return correct[:500] },
+		},
+		{
+			name:        "complete destination",
+			correctPath: "testdata/out_offset0_limit1000.txt",
+			limit:       1000,
+			partial:     func(correct []byte) []byte { return correct },
+		},
+		{
+			name:        "corrupted destination",
+			correctPath: "testdata/out_offset0_limit0.txt",
+			partial: func(correct []byte) []byte {
+				corrupted := append([]byte(nil), correct[:1000]...)
+				corrupted[10] ^= 0xff
+				return corrupted
+			},
+		},
+		{
+			name:        "unflushed tail after checkpoint",
+			correctPath: "testdata/out_offset0_limit0.txt",
+			partial: func(correct []byte) []byte {
+				return append(append([]byte(nil), correct[:3000]...), make([]byte, 100)...)
+			},
+			checkpoint: &Checkpoint{Copied: 3000},
+		},
+		{
+			name:        "checkpoint of another copy",
+			correctPath: "testdata/out_offset0_limit0.txt",
+			partial:     func(correct []byte) []byte { return correct[:1000] },
+			checkpoint:  &Checkpoint{Offset: 100, Copied: 1000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toPath := filepath.Join(t.TempDir(), "out.txt")
+			correct, err := os.ReadFile(tt.correctPath)
+			require.NoError(t, err)
+
+			partPath := toPath + PartialSuffix
+			if tt.partial != nil {
+				require.NoError(t, os.WriteFile(partPath, tt.partial(correct), 0o644))
+			}
+			if tt.checkpoint != nil {
+				cp := *tt.checkpoint
+				cp.From, err = filepath.Abs("testdata/input.txt")
+				require.NoError(t, err)
+				require.NoError(t, writeCheckpoint(checkpointPath(partPath), cp))
+			}
+
+			err = Copy("testdata/input.txt", toPath, tt.offset, tt.limit, WithResume())
+			require.NoError(t, err)
+
+			data, err := os.ReadFile(toPath)
+			require.NoError(t, err)
+			require.Equal(t, correct, data)
+
+			_, err = os.Stat(checkpointPath(partPath))
+			require.ErrorIs(t, err, os.ErrNotExist, "checkpoint was not removed")
+			_, err = os.Stat(partPath)
+			require.ErrorIs(t, err, os.ErrNotExist, "partial file was not renamed")
+		})
+	}
+}
+
+func TestResumePosition(t *testing.T) {
+	dir := t.TempDir()
+	fromPath := "testdata/input.txt"
+	toPath := filepath.Join(dir, "out.txt")
+
+	correct, err := os.ReadFile("testdata/out_offset100_limit1000.txt")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(toPath, correct[:700], 0o644))
+
+	fromFile, err := os.Open(fromPath)
+	require.NoError(t, err)
+	defer fromFile.Close()
+	toFile, err := os.Open(toPath)
+	require.NoError(t, err)
+	defer toFile.Close()
+
+	cp := Checkpoint{From: "from", Offset: 100, Limit: 1000}
+
+	copied, err := resumePosition(fromFile, toFile, cp, toPath, 1000, discardLogger)
+	require.NoError(t, err)
+	require.Equal(t, int64(700), copied)
+
+	// The checkpoint limits the prefix to the flushed part.
+	saved := cp
+	saved.Copied = 400
+	require.NoError(t, writeCheckpoint(checkpointPath(toPath), saved))
+	copied, err = resumePosition(fromFile, toFile, cp, toPath, 1000, discardLogger)
+	require.NoError(t, err)
+	require.Equal(t, int64(400), copied)
+
+	// The prefix of another range does not match.
+	cp.Offset = 0
+	saved.Offset = 0
+	require.NoError(t, writeCheckpoint(checkpointPath(toPath), saved))
+	copied, err = resumePosition(fromFile, toFile, cp, toPath, 1000, discardLogger)
+	require.NoError(t, err)
+	require.Zero(t, copied)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// StdStream is the path which stands for stdin as source and stdout as destination.
+const StdStream = "-"
+
+var ErrUnsupportedStream = errors.New("option is not supported for streams")
+
+// Check the source is a pipe or a device which can't be seeked or sized.
+func isStream(fileInfo os.FileInfo) bool {
+	return !fileInfo.Mode().IsRegular()
+}
+
+// Get source file info, stdin for "-".
+func statSource(fromPath string) (os.FileInfo, error) {
+	if fromPath == StdStream {
+		return os.Stdin.Stat()
+	}
+	return os.Stat(fromPath)
+}
+
+// Open source file, stdin for "-".
+func openSource(fromPath string) (*os.File, error) {
+	if fromPath == StdStream {
+		return os.Stdin, nil
+	}
+	return os.Open(fromPath)
+}
+
+// Close source file, stdin is left open.
+func closeSource(file *os.File) {
+	if file != os.Stdin {
+		file.Close()
+	}
+}
+
+// Resolve symbolic links of the destination, so the file they point to is replaced.
+func resolveDestination(toPath string) (string, error) {
+	if toPath == StdStream {
+		return toPath, nil
+	}
+	info, err := os.Lstat(toPath)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return toPath, nil
+	}
+	return filepath.EvalSymlinks(toPath)
+}
+
+// Check the destination is written in place instead of being replaced: stdout or
+// an existing device or pipe. Only a missing or regular file is replaced.
+func isInPlace(toPath string) bool {
+	if toPath == StdStream {
+		return true
+	}
+	info, err := os.Lstat(toPath)
+	return err == nil && !info.Mode().IsRegular()
+}
+
+// Check the options can be used with streams. Resume needs to reread the source
+// and the destination and verify needs to reread the destination.
+func checkStreams(o *options, stream bool, toPath string) error {
+	inPlace := isInPlace(toPath)
+	if o.resume && (stream || inPlace) {
+		return ErrUnsupportedStream
+	}
+	if o.verify && inPlace {
+		return ErrUnsupportedStream
+	}
+	return nil
+}
+
+// Skip offset bytes of the source which can't be seeked.
+func skipOffset(r io.Reader, offset int64) error {
+	_, err := io.CopyN(io.Discard, r, offset)
+	if errors.Is(err, io.EOF) {
+		return ErrOffsetExceedsFileSize
+	}
+	return err
+}
+
+// Open destination: stdout for "-", the file itself if it is written in place or
+// temporary file replacing the destination on commit. Reports whether the temporary file is used.
+func openDestination(toPath string) (*os.File, bool, error) {
+	if toPath == StdStream {
+		return os.Stdout, false, nil
+	}
+	if isInPlace(toPath) {
+		// Opening a pipe for write waits for its reader
+		file, err := os.OpenFile(toPath, os.O_WRONLY|os.O_TRUNC, 0)
+		return file, false, err
+	}
+	file, err := createTemp(toPath)
+	return file, err == nil, err
+}
+
+// Replace the destination by the written temporary file or close the file written in place.
+func commitDestination(file *os.File, toPath string, temp bool) error {
+	switch {
+	case temp:
+		return commitFile(file, toPath)
+	case file == os.Stdout:
+		return nil
+	}
+	return file.Close()
+}
+
+// Abandon the temporary file of a failed copy or close the file written in place.
+func abandonDestination(o *options, file *os.File, toPath string, temp bool) {
+	switch {
+	case temp:
+		abandonTemp(o, file, toPath)
+	case file != os.Stdout:
+		file.Close()
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyDevice(t *testing.T) {
+	toPath := filepath.Join(t.TempDir(), "out.txt")
+
+	t.Run("limit", func(t *testing.T) {
+		err := Copy("/dev/urandom", toPath, 0, 100)
+		require.NoError(t, err)
+
+		size, err := GetFileSize(toPath)
+		require.NoError(t, err)
+		require.Equal(t, int64(100), size)
+	})
+
+	t.Run("offset and limit", func(t *testing.T) {
+		err := Copy("/dev/zero", toPath, 10, 5)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(toPath)
+		require.NoError(t, err)
+		require.Equal(t, make([]byte, 5), data)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		err := Copy("/dev/zero", toPath, 0, 5, WithResume())
+		require.ErrorIs(t, err, ErrUnsupportedStream)
+	})
+}
+
+func TestCopyFIFO(t *testing.T) {
+	// Write data to a new FIFO, opening for write blocks until Copy opens it for read.
+	fifo := func(t *testing.T, data string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "fifo")
+		require.NoError(t, syscall.Mkfifo(path, 0o600))
+		go func() {
+			f, err := os.OpenFile(path, os.O_WRONLY, 0)
+			if err != nil {
+				return
+			}
+			defer f.Close()
+			f.WriteString(data)
+		}()
+		return path
+	}
+	toPath := filepath.Join(t.TempDir(), "out.txt")
+
+	t.Run("until EOF", func(t *testing.T) {
+		err := Copy(fifo(t, "hello, fifo"), toPath, 7, 0)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(toPath)
+		require.NoError(t, err)
+		require.Equal(t, "fifo", string(data))
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		err := Copy(fifo(t, "hello, fifo"), toPath, 0, 5)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(toPath)
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(data))
+	})
+
+	t.Run("offset exceeds stream", func(t *testing.T) {
+		err := Copy(fifo(t, "hello"), toPath, 10, 0)
+		require.ErrorIs(t, err, ErrOffsetExceedsFileSize)
+	})
+}
+
+func TestCopyInPlace(t *testing.T) {
+	input, err := os.ReadFile("testdata/input.txt")
+	require.NoError(t, err)
+
+	t.Run("fifo", func(t *testing.T) {
+		toPath := filepath.Join(t.TempDir(), "fifo")
+		require.NoError(t, syscall.Mkfifo(toPath, 0o600))
+		received := make(chan []byte)
+		go func() {
+			f, err := os.Open(toPath)
+			if err != nil {
+				close(received)
+				return
+			}
+			defer f.Close()
+			data, _ := io.ReadAll(f)
+			received <- data
+		}()
+
+		err := Copy("testdata/input.txt", toPath, 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, input, <-received)
+
+		info, err := os.Lstat(toPath)
+		require.NoError(t, err)
+		require.Equal(t, os.ModeNamedPipe, info.Mode().Type(), "fifo was replaced")
+
+		err = Copy("testdata/input.txt", toPath, 0, 0, WithResume())
+		require.ErrorIs(t, err, ErrUnsupportedStream)
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "target.txt")
+		toPath := filepath.Join(dir, "link.txt")
+		require.NoError(t, os.WriteFile(target, []byte("old content"), 0o644))
+		require.NoError(t, os.Symlink(target, toPath))
+
+		err := Copy("testdata/input.txt", toPath, 0, 0)
+		require.NoError(t, err)
+
+		info, err := os.Lstat(toPath)
+		require.NoError(t, err)
+		require.Equal(t, os.ModeSymlink, info.Mode().Type(), "symlink was replaced")
+		data, err := os.ReadFile(target)
+		require.NoError(t, err)
+		require.Equal(t, input, data)
+		requireFiles(t, dir, "target.txt", "link.txt")
+
+		// The target is replaced atomically, a failed copy leaves it untouched
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = CopyContext(ctx, "testdata/input.txt", toPath, 0, 0)
+		require.ErrorIs(t, err, context.Canceled)
+		data, err = os.ReadFile(target)
+		require.NoError(t, err)
+		require.Equal(t, input, data)
+	})
+}
+
+func TestCopyStdStreams(t *testing.T) {
+	stdin, stdout := os.Stdin, os.Stdout
+	t.Cleanup(func() {
+		os.Stdin, os.Stdout = stdin, stdout
+	})
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	go func() {
+		w.WriteString("hello, stdin")
+		w.Close()
+	}()
+	out, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	require.NoError(t, err)
+	defer out.Close()
+	os.Stdin, os.Stdout = r, out
+
+	err = Copy(StdStream, StdStream, 7, 0)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(out.Name())
+	require.NoError(t, err)
+	require.Equal(t, "stdin", string(data))
+
+	err = Copy("testdata/input.txt", StdStream, 0, 0, WithChecksum(ChecksumSHA256, nil), WithVerify())
+	require.ErrorIs(t, err, ErrUnsupportedStream)
+}
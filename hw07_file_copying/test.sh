@@ -21,5 +21,56 @@ cmp out.txt testdata/out_offset100_limit1000.txt
 ./go-cp -from testdata/input.txt -to out.txt -offset 6000 -limit 1000
 cmp out.txt testdata/out_offset6000_limit1000.txt
 
-rm -f go-cp out.txt
+./go-cp -from testdata/input.txt -to out.txt -checksum sha256 -checksum-file out.txt.sha256 -verify
+sha256sum -c out.txt.sha256
+
+cat testdata/input.txt | ./go-cp -from - -to - -offset 100 -limit 1000 > out.txt
+cmp out.txt testdata/out_offset100_limit1000.txt
+
+./go-cp -from /dev/urandom -to out.txt -limit 1000
+test "$(stat -c %s out.txt)" -eq 1000
+
+./go-cp -from testdata/input.txt -to out.txt -offset 100 -limit 1000 -workers 4
+cmp out.txt testdata/out_offset100_limit1000.txt
+
+./go-cp -from testdata/input.txt -to out.txt -offset 100 -limit 1000 -rate 1MB/s
+cmp out.txt testdata/out_offset100_limit1000.txt
+
+./go-cp -from testdata/input.txt -to out.txt -progress json -log-format json -quiet 2> progress.json
+cmp out.txt testdata/out_offset0_limit0.txt
+tail -n 1 progress.json | grep -q "\"done\":true"
+
+./go-cp -from testdata/input.txt -to out.txt -range 100-1099
+cmp out.txt testdata/out_offset100_limit1000.txt
+
+./go-cp -from testdata/input.txt -to out.txt -offset -1000
+cmp out.txt <(tail -c 1000 testdata/input.txt)
+
+./go-cp -from testdata/input.txt -to out.txt.zst -offset 100 -limit 1000 -compress zstd
+./go-cp -from out.txt.zst -to out.txt -decompress
+cmp out.txt testdata/out_offset100_limit1000.txt
+
+./go-cp -from testdata/input.txt -to out.txt.gz -compress gzip
+gzip -dc out.txt.gz | cmp - testdata/out_offset0_limit0.txt
+
+rc=0; ./go-cp -from testdata/input.txt -to out.txt -offset 10000 || rc=$?
+test "$rc" -eq 4
+rc=0; ./go-cp -from testdata -to out.txt || rc=$?
+test "$rc" -eq 3
+rc=0; ./go-cp -from testdata/input.txt -to out.txt -limit -1 || rc=$?
+test "$rc" -eq 2
+
+rc=0; timeout --preserve-status -s INT 1 ./go-cp -from /dev/zero -to out.bin -limit 100000000 -rate 1MB/s -keep-partial || rc=$?
+test "$rc" -eq 130
+test -s out.bin.part
+test ! -e out.bin
+
+rc=0; timeout --preserve-status -s TERM 1 ./go-cp -from /dev/zero -to out.bin -limit 100000000 -rate 1MB/s || rc=$?
+test "$rc" -eq 143
+test ! -e out.bin
+
+./go-cp -from testdata -to out_dir -recursive
+diff -r testdata out_dir
+
+rm -rf go-cp out.txt out.bin.part out.txt.zst out.txt.gz progress.json out.txt.sha256 out_dir
 echo "PASS"
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRate = errors.New("invalid rate")
+
+// Clock is the time source of the rate limit.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// Multipliers of the rate units.
+var rateUnits = map[string]float64{
+	"":    1,
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+}
+
+// ParseRate parses bandwidth in bytes per second like 10MB/s, 512KiB/s or 1000.
+func ParseRate(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSuffix(strings.TrimSpace(s), "/s"))
+	unitStart := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if unitStart < 0 {
+		unitStart = len(value)
+	}
+
+	multiplier, ok := rateUnits[strings.TrimSpace(value[unitStart:])]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown unit in %q", ErrInvalidRate, s)
+	}
+	number, err := strconv.ParseFloat(value[:unitStart], 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	rate := int64(number * multiplier)
+	if rate <= 0 {
+		return 0, fmt.Errorf("%w: %q must be positive", ErrInvalidRate, s)
+	}
+	return rate, nil
+}
+
+// WithRate limits the copy bandwidth to rate bytes per second.
+// Reads through the limit are copied by the loop, the fast paths are not used.
+func WithRate(rate int64) Option {
+	return func(o *options) {
+		o.rate = rate
+	}
+}
+
+// WithClock sets the clock of the rate limit.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// Wrap the reader into the rate limit if it is set.
+func (o *options) throttle(r io.Reader) io.Reader {
+	if o.rate <= 0 {
+		return r
+	}
+	clock := o.clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	return newThrottledReader(r, o.rate, clock)
+}
+
+// Reader limited by a token bucket of one second of rate. The bucket starts full,
+// read bytes are taken from it and the reader sleeps while it is in debt.
+type throttledReader struct {
+	r      io.Reader
+	rate   int64
+	clock  Clock
+	tokens float64
+	last   time.Time
+}
+
+func newThrottledReader(r io.Reader, rate int64, clock Clock) *throttledReader {
+	return &throttledReader{r: r, rate: rate, clock: clock, tokens: float64(rate), last: clock.Now()}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > t.rate {
+		p = p[:t.rate]
+	}
+
+	now := t.clock.Now()
+	t.tokens = min(float64(t.rate), t.tokens+now.Sub(t.last).Seconds()*float64(t.rate))
+	t.last = now
+
+	n, err := t.r.Read(p)
+	t.tokens -= float64(n)
+	if t.tokens < 0 {
+		t.clock.Sleep(time.Duration(-t.tokens / float64(t.rate) * float64(time.Second)))
+	}
+	return n, err
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Clock moving forward only when slept.
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+	c.slept += d
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		rate     string
+		expected int64
+	}{
+		{rate: "1000", expected: 1000},
+		{rate: "10MB/s", expected: 10_000_000},
+		{rate: "512KiB/s", expected: 512 << 10},
+		{rate: "1.5kb/s", expected: 1500},
+		{rate: "2 GiB", expected: 2 << 30},
+		{rate: "100B/s", expected: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rate, func(t *testing.T) {
+			rate, err := ParseRate(tt.rate)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, rate)
+		})
+	}
+
+	for _, rate := range []string{"", "fast", "10XB/s", "0MB/s", "-1MB/s", "MB/s"} {
+		t.Run("invalid "+rate, func(t *testing.T) {
+			_, err := ParseRate(rate)
+			require.ErrorIs(t, err, ErrInvalidRate)
+		})
+	}
+}
+
+func TestThrottledReader(t *testing.T) {
+	t.Run("rate", func(t *testing.T) {
+		clock := &fakeClock{}
+		data := bytes.Repeat([]byte{1}, 10000)
+		r := newThrottledReader(bytes.NewReader(data), 1000, clock)
+
+		copied, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, data, copied)
+		// The first second is the full bucket
+		require.Equal(t, 9*time.Second, clock.slept)
+	})
+
+	t.Run("idle time refills bucket", func(t *testing.T) {
+		clock := &fakeClock{}
+		r := newThrottledReader(bytes.NewReader(make([]byte, 3000)), 1000, clock)
+		buf := make([]byte, 1000)
+
+		_, err := r.Read(buf)
+		require.NoError(t, err)
+		clock.now = clock.now.Add(time.Hour)
+		_, err = r.Read(buf)
+		require.NoError(t, err)
+		require.Zero(t, clock.slept)
+
+		_, err = r.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, time.Second, clock.slept)
+	})
+}
+
+func TestCopyRate(t *testing.T) {
+	clock := &fakeClock{}
+	toPath := filepath.Join(t.TempDir(), "out.txt")
+
+	err := Copy("testdata/input.txt", toPath, 100, 1000, WithRate(100), WithClock(clock))
+	require.NoError(t, err)
+	require.Equal(t, 9*time.Second, clock.slept)
+
+	data, err := os.ReadFile(toPath)
+	require.NoError(t, err)
+	correctData, err := os.ReadFile("testdata/out_offset100_limit1000.txt")
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(data, correctData), "file contents are not equal")
+}
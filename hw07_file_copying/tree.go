@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SymlinkPolicy defines how CopyTree copies symbolic links.
+type SymlinkPolicy int
+
+const (
+	// SymlinkPreserve recreates links with the same targets.
+	SymlinkPreserve SymlinkPolicy = iota
+	// SymlinkFollow copies files and directories the links point to.
+	SymlinkFollow
+)
+
+// ExistingPolicy defines what CopyTree does with files which exist in the destination.
+type ExistingPolicy int
+
+const (
+	ExistingOverwrite ExistingPolicy = iota
+	ExistingSkip
+)
+
+var (
+	ErrUnsupportedPolicy     = errors.New("unsupported policy")
+	ErrUnsupportedTreeOption = errors.New("option is not supported for directories")
+	ErrNestedDestination     = errors.New("destination is inside source directory")
+	ErrSymlinkLoop           = errors.New("symbolic link loop")
+)
+
+// ParseSymlinkPolicy parses preserve or follow.
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	switch s {
+	case "preserve":
+		return SymlinkPreserve, nil
+	case "follow":
+		return SymlinkFollow, nil
+	default:
+		return 0, fmt.Errorf("%w: symlinks %q", ErrUnsupportedPolicy, s)
+	}
+}
+
+// ParseExistingPolicy parses overwrite or skip.
+func ParseExistingPolicy(s string) (ExistingPolicy, error) {
+	switch s {
+	case "overwrite":
+		return ExistingOverwrite, nil
+	case "skip":
+		return ExistingSkip, nil
+	default:
+		return 0, fmt.Errorf("%w: existing %q", ErrUnsupportedPolicy, s)
+	}
+}
+
+// WithSymlinks sets how CopyTree copies symbolic links, they are preserved by default.
+func WithSymlinks(policy SymlinkPolicy) Option {
+	return func(o *options) {
+		o.symlinks = policy
+	}
+}
+
+// WithExisting sets what CopyTree does with existing files, they are overwritten by default.
+func WithExisting(policy ExistingPolicy) Option {
+	return func(o *options) {
+		o.existing = policy
+	}
+}
+
+// Entry of the source tree, link is set for preserved symbolic links.
+type treeEntry struct {
+	rel  string
+	info os.FileInfo
+	link string
+}
+
+// CopyTree copies the directory recursively keeping permissions and modification times.
+// Files are copied atomically one by one, the progress is shown for all files together.
+func CopyTree(fromDir, toDir string, opts ...Option) error {
+	return CopyTreeContext(context.Background(), fromDir, toDir, opts...)
+}
+
+// CopyTreeContext copies the directory until the context is done, it is checked between reads.
+func CopyTreeContext(ctx context.Context, fromDir, toDir string, opts ...Option) error {
+	o := applyOptions(opts)
+
+	if err := checkTreeArgs(&o, fromDir, toDir); err != nil {
+		o.logger.Error("Error validating arguments", "error", err)
+		return err
+	}
+
+	rootInfo, err := os.Stat(fromDir)
+	if err != nil {
+		o.logger.Error("Error checking source directory", "error", err)
+		return err
+	}
+	if !rootInfo.IsDir() {
+		o.logger.Error("Error checking source directory", "error", ErrUnsupportedFile)
+		return ErrUnsupportedFile
+	}
+
+	entries, err := walkTree(fromDir, "", o.symlinks == SymlinkFollow, []os.FileInfo{rootInfo}, nil)
+	if err != nil {
+		o.logger.Error("Error reading source directory", "error", err)
+		return err
+	}
+	var total int64
+	for _, e := range entries {
+		if e.info.Mode().IsRegular() {
+			total += e.info.Size()
+		}
+	}
+	o.logger.Info("Source directory read", "path", fromDir, "entries", len(entries), "bytes", total)
+
+	// Directories are writable while copying, their modes are set at the end
+	if err := os.MkdirAll(toDir, 0o700); err != nil {
+		o.logger.Error("Error creating destination directory", "error", err)
+		return err
+	}
+	dirs := []treeEntry{{info: rootInfo}}
+
+	o.progress.Start(total)
+	for _, e := range entries {
+		src, dst := filepath.Join(fromDir, e.rel), filepath.Join(toDir, e.rel)
+		if e.info.IsDir() {
+			if err := os.Mkdir(dst, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+				o.logger.Error("Error creating directory", "error", err, "path", dst)
+				return err
+			}
+			dirs = append(dirs, e)
+			continue
+		}
+		if err := copyTreeEntry(ctx, &o, src, dst, e); err != nil {
+			if ctx.Err() != nil {
+				o.logger.Warn("Copy cancelled", "error", err, "path", src)
+				return err
+			}
+			o.logger.Error("Error copying file", "error", err, "path", src)
+			return err
+		}
+	}
+
+	// Copying into a directory changes its modification time, so children go first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := setAttributes(filepath.Join(toDir, dirs[i].rel), dirs[i].info); err != nil {
+			o.logger.Error("Error setting directory attributes", "error", err)
+			return err
+		}
+	}
+
+	o.progress.Finish()
+	o.logger.Info("Directory copied successfully", "from", fromDir, "to", toDir)
+
+	return nil
+}
+
+// Check arguments of the tree copy.
+func checkTreeArgs(o *options, fromDir, toDir string) error {
+	if o.resume || o.keepPartial || o.checksum != "" || o.verify || o.transforms() || o.workers > 1 || o.rate > 0 {
+		return ErrUnsupportedTreeOption
+	}
+	if fromDir == "" {
+		return ErrEmptySource
+	}
+	if toDir == "" {
+		return ErrEmptyDestination
+	}
+
+	absFrom, err := filepath.Abs(fromDir)
+	if err != nil {
+		return err
+	}
+	absTo, err := filepath.Abs(toDir)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(absFrom, absTo)
+	if err != nil {
+		return err
+	}
+	if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ErrNestedDestination
+	}
+	return nil
+}
+
+// Collect entries of the tree in pre-order. Links to directories are walked when followed,
+// the ancestors are kept to detect loops.
+func walkTree(root, rel string, follow bool, ancestors []os.FileInfo, entries []treeEntry) ([]treeEntry, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(root, rel))
+	if err != nil {
+		return nil, err
+	}
+	for _, de := range dirEntries {
+		entryRel := filepath.Join(rel, de.Name())
+		path := filepath.Join(root, entryRel)
+		info, err := os.Lstat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !follow {
+				link, err := os.Readlink(path)
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, treeEntry{rel: entryRel, info: info, link: link})
+				continue
+			}
+			if info, err = os.Stat(path); err != nil {
+				return nil, err
+			}
+		}
+
+		entries = append(entries, treeEntry{rel: entryRel, info: info})
+		if !info.IsDir() {
+			continue
+		}
+		for _, ancestor := range ancestors {
+			if os.SameFile(ancestor, info) {
+				return nil, fmt.Errorf("%w: %s", ErrSymlinkLoop, path)
+			}
+		}
+		entries, err = walkTree(root, entryRel, follow, append(ancestors, info), entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Copy a file or recreate a symbolic link according to the existing files policy.
+func copyTreeEntry(ctx context.Context, o *options, src, dst string, e treeEntry) error {
+	mode := e.info.Mode()
+	if mode&os.ModeSymlink == 0 && !mode.IsRegular() {
+		o.logger.Warn("Skipping unsupported file", "path", src)
+		return nil
+	}
+
+	if _, err := os.Lstat(dst); err == nil {
+		if o.existing == ExistingSkip {
+			o.logger.Info("Skipping existing file", "path", dst)
+			if mode.IsRegular() {
+				o.progress.Add(e.info.Size())
+			}
+			return nil
+		}
+		if e.link != "" {
+			if err := os.Remove(dst); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Link modification times can't be set without lutimes, so only the target is kept
+	if e.link != "" {
+		return os.Symlink(e.link, dst)
+	}
+	return copyTreeFile(ctx, src, dst, e.info, o)
+}
+
+// Copy regular file through a temporary file and set its attributes.
+func copyTreeFile(ctx context.Context, src, dst string, info os.FileInfo, o *options) error {
+	fromFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer fromFile.Close()
+
+	toFile, err := createTemp(dst)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			abandonTemp(o, toFile, dst)
+		}
+	}()
+
+	fast, err := copyFast(ctx, toFile, fromFile, 0, info.Size(), o.progress.Add)
+	if !fast {
+		source := &contextReader{ctx: ctx, r: fromFile}
+		_, err = io.Copy(toFile, &progressReader{r: source, progress: o.progress.Add})
+	}
+	if err != nil {
+		return err
+	}
+	if err := commitFile(toFile, dst); err != nil {
+		return err
+	}
+	committed = true
+
+	return setAttributes(dst, info)
+}
+
+// Set permissions and modification time of the source, access time is left unchanged.
+func setAttributes(path string, info os.FileInfo) error {
+	if err := os.Chmod(path, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(path, time.Time{}, info.ModTime())
+}
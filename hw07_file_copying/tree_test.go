@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Create source tree:
+//
+//	a/b.txt
+//	a/c/d.txt
+//	link -> a/b.txt
+//	dirlink -> a
+func makeTree(t *testing.T) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "src")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "c"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "b.txt"), []byte("bbb"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "c", "d.txt"), []byte("dddd"), 0o640))
+	require.NoError(t, os.Symlink(filepath.Join("a", "b.txt"), filepath.Join(root, "link")))
+	require.NoError(t, os.Symlink("a", filepath.Join(root, "dirlink")))
+
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "a", "b.txt"), time.Time{}, mtime))
+	require.NoError(t, os.Chmod(filepath.Join(root, "a", "c"), 0o750))
+	require.NoError(t, os.Chtimes(filepath.Join(root, "a", "c"), time.Time{}, mtime))
+	return root
+}
+
+func TestCopyTree(t *testing.T) {
+	t.Run("preserve symlinks", func(t *testing.T) {
+		from := makeTree(t)
+		to := filepath.Join(t.TempDir(), "dst")
+
+		err := CopyTree(from, to)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(to, "a", "c", "d.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "dddd", string(data))
+
+		info, err := os.Stat(filepath.Join(to, "a", "b.txt"))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		require.True(t, info.ModTime().Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+		info, err = os.Stat(filepath.Join(to, "a", "c"))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o750), info.Mode().Perm())
+		require.True(t, info.ModTime().Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+		link, err := os.Readlink(filepath.Join(to, "link"))
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join("a", "b.txt"), link)
+		link, err = os.Readlink(filepath.Join(to, "dirlink"))
+		require.NoError(t, err)
+		require.Equal(t, "a", link)
+	})
+
+	t.Run("follow symlinks", func(t *testing.T) {
+		from := makeTree(t)
+		to := filepath.Join(t.TempDir(), "dst")
+
+		err := CopyTree(from, to, WithSymlinks(SymlinkFollow))
+		require.NoError(t, err)
+
+		info, err := os.Lstat(filepath.Join(to, "link"))
+		require.NoError(t, err)
+		require.True(t, info.Mode().IsRegular())
+
+		data, err := os.ReadFile(filepath.Join(to, "dirlink", "c", "d.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "dddd", string(data))
+	})
+
+	t.Run("symlink loop", func(t *testing.T) {
+		from := makeTree(t)
+		require.NoError(t, os.Symlink("..", filepath.Join(from, "a", "up")))
+
+		err := CopyTree(from, filepath.Join(t.TempDir(), "dst"), WithSymlinks(SymlinkFollow))
+		require.ErrorIs(t, err, ErrSymlinkLoop)
+	})
+
+	t.Run("existing files", func(t *testing.T) {
+		from := makeTree(t)
+		to := filepath.Join(t.TempDir(), "dst")
+		existingPath := filepath.Join(to, "a", "b.txt")
+		require.NoError(t, os.MkdirAll(filepath.Dir(existingPath), 0o755))
+		require.NoError(t, os.WriteFile(existingPath, []byte("old"), 0o644))
+		require.NoError(t, os.Symlink("old", filepath.Join(to, "link")))
+
+		err := CopyTree(from, to, WithExisting(ExistingSkip))
+		require.NoError(t, err)
+		data, err := os.ReadFile(existingPath)
+		require.NoError(t, err)
+		require.Equal(t, "old", string(data))
+		link, err := os.Readlink(filepath.Join(to, "link"))
+		require.NoError(t, err)
+		require.Equal(t, "old", link)
+
+		err = CopyTree(from, to, WithExisting(ExistingOverwrite))
+		require.NoError(t, err)
+		data, err = os.ReadFile(existingPath)
+		require.NoError(t, err)
+		require.Equal(t, "bbb", string(data))
+		link, err = os.Readlink(filepath.Join(to, "link"))
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join("a", "b.txt"), link)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		from := makeTree(t)
+
+		err := CopyTree(from, filepath.Join(from, "a", "dst"))
+		require.ErrorIs(t, err, ErrNestedDestination)
+
+		err = CopyTree("testdata/input.txt", filepath.Join(t.TempDir(), "dst"))
+		require.ErrorIs(t, err, ErrUnsupportedFile)
+
+		err = CopyTree(from, filepath.Join(t.TempDir(), "dst"), WithResume())
+		require.ErrorIs(t, err, ErrUnsupportedTreeOption)
+
+		err = CopyTree(from, filepath.Join(t.TempDir(), "dst"), WithWorkers(4))
+		require.ErrorIs(t, err, ErrUnsupportedTreeOption)
+
+		err = CopyTree(from, filepath.Join(t.TempDir(), "dst"), WithRate(1024))
+		require.ErrorIs(t, err, ErrUnsupportedTreeOption)
+
+		err = CopyTree(from, filepath.Join(t.TempDir(), "dst"), WithKeepPartial())
+		require.ErrorIs(t, err, ErrUnsupportedTreeOption)
+	})
+}
+
+func TestParsePolicies(t *testing.T) {
+	symlinks, err := ParseSymlinkPolicy("follow")
+	require.NoError(t, err)
+	require.Equal(t, SymlinkFollow, symlinks)
+	_, err = ParseSymlinkPolicy("copy")
+	require.ErrorIs(t, err, ErrUnsupportedPolicy)
+
+	existing, err := ParseExistingPolicy("skip")
+	require.NoError(t, err)
+	require.Equal(t, ExistingSkip, existing)
+	_, err = ParseExistingPolicy("keep")
+	require.ErrorIs(t, err, ErrUnsupportedPolicy)
+}